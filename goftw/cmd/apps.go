@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"goftw/internal/bench"
+	"goftw/internal/config"
+	"goftw/internal/db"
+	"goftw/internal/state"
+	"goftw/internal/updates"
+)
+
+// appsCommand handles `goftw-entry apps list` and `goftw-entry apps update [app...]`.
+// A manual update ignores update windows and moves manual apps, but never pinned ones.
+func appsCommand(args []string, instanceCfg *config.InstanceConfig, benchDir string, dbCfg db.Config) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: apps list | apps update [app...]")
+	}
+
+	switch args[0] {
+	case "list":
+		apps, err := bench.ListApps(benchDir)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "APP\tPOLICY\tCOMMIT")
+		for _, app := range apps {
+			commit, err := bench.AppCommit(benchDir, app)
+			if err != nil {
+				commit = "?"
+			}
+			policy := updates.Policy(instanceCfg.Updates, app)
+			if bench.IsLocalApp(benchDir, app) {
+				policy, commit = "local", "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%.8s\n", app, policy, commit)
+		}
+		return w.Flush()
+
+	case "update":
+		// Do not interleave with hot reloads, the drift watcher or a window update
+		unlock, err := state.Lock("reconcile")
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		return updates.Run(instanceCfg, benchDir, dbCfg, args[1:], true)
+	}
+	return fmt.Errorf("unknown apps command %q", args[0])
+}
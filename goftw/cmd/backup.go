@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"goftw/internal/backup"
+	"goftw/internal/config"
+)
+
+// backupCommand handles `goftw-entry backup run [site...]` and `goftw-entry backup list [site]`.
+func backupCommand(args []string, instanceCfg *config.InstanceConfig, commonCfg *config.CommonConfig) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: backup run [site...] | backup list [site]")
+	}
+
+	switch args[0] {
+	case "run":
+		targets, err := selectSites(instanceCfg, args[1:])
+		if err != nil {
+			return err
+		}
+		failed := 0
+		for _, site := range targets {
+			if _, err := backup.RunAndPrune(site, instanceCfg, commonCfg, "manual"); err != nil {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d backups failed", failed, len(targets))
+		}
+		return nil
+
+	case "list":
+		site := ""
+		if len(args) > 1 {
+			site = args[1]
+		}
+		entries, err := backup.List(site)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tKIND\tCREATED\tFILES\tSIZE")
+		for _, e := range entries {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\n", e.ID, e.Kind, e.CreatedAt.Local().Format("2006-01-02 15:04:05"), len(e.Files), e.Size)
+		}
+		return w.Flush()
+	}
+	return fmt.Errorf("unknown backup command %q", args[0])
+}
+
+// selectSites returns the named sites from instance.json, or all of them when none are named.
+func selectSites(instanceCfg *config.InstanceConfig, names []string) ([]config.InstanceSite, error) {
+	if len(names) == 0 {
+		return instanceCfg.InstanceSites, nil
+	}
+	var out []config.InstanceSite
+	for _, name := range names {
+		found := false
+		for _, site := range instanceCfg.InstanceSites {
+			if site.SiteName == name {
+				out = append(out, site)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("site %s is not listed in instance.json", name)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+
+	"goftw/internal/bench"
+	"goftw/internal/config"
+)
+
+// bundleCommand handles `goftw-entry bundle export [--skip-local-apps] <file>` and
+// `goftw-entry bundle import <file>`. Import builds the bench from the bundle and requires
+// that it does not exist yet.
+func bundleCommand(args []string, instanceCfg *config.InstanceConfig, benchDir string) error {
+	usage := fmt.Errorf("usage: bundle export [--skip-local-apps] <file> | bundle import <file>")
+	if len(args) == 3 && args[0] == "export" && args[1] == "--skip-local-apps" {
+		return bench.ExportBundle(benchDir, instanceCfg.FrappeBranch, args[2], true)
+	}
+	if len(args) != 2 {
+		return usage
+	}
+	switch args[0] {
+	case "export":
+		return bench.ExportBundle(benchDir, instanceCfg.FrappeBranch, args[1], false)
+	case "import":
+		return bench.ImportBundle(args[1], benchDir)
+	}
+	return usage
+}
@@ -1,18 +1,25 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
 
+	"goftw/internal/backup"
 	"goftw/internal/bench"
+	"goftw/internal/certs"
 	"goftw/internal/config"
 	"goftw/internal/db"
 
 	internalDeploy "goftw/internal/deploy"
 	"goftw/internal/environ"
 	"goftw/internal/redis"
+	"goftw/internal/reload"
 	"goftw/internal/sites"
+	"goftw/internal/supervisor"
+	"goftw/internal/updates"
+	"goftw/internal/watch"
 )
 
 func main() {
@@ -38,6 +45,9 @@ func main() {
 		log.Fatalf("failed to load instance.json: %v", err)
 		os.Exit(1)
 	}
+	if err := instanceCfx.Validate(); err != nil {
+		log.Fatalf("%v", err)
+	}
 
 	// Load common_site_config.json
 	commonCfg, err := config.LoadCommonSitesConfig(environ.GetCommonSitesConfigPath())
@@ -47,6 +57,55 @@ func main() {
 	}
 	benchDir := environ.GetBenchPath()
 	deployment := instanceCfx.Deployment
+	backup.SetSafetyPolicy(instanceCfx.SafetyBackups)
+	bench.SetMirrors(instanceCfx.Mirrors)
+	bench.SetLocalApps(instanceCfx.LocalApps)
+	bench.SetPinnedRefs(updates.PinnedRefs(instanceCfx.Updates))
+
+	// ---------------------------
+	// Worker processes (services were already awaited by the parent)
+	// ---------------------------
+	if handled, err := workerCommand(os.Args[1:], instanceCfx, benchDir, dbCfg); handled {
+		if path := os.Getenv(sites.ResultFileEnv); path != "" {
+			if werr := sites.WriteResults(path); werr != nil {
+				fmt.Printf("[ERROR] Failed to write worker results: %v\n", werr)
+			}
+		}
+		if err != nil {
+			log.Fatalf("%s failed: %v", os.Args[1], err)
+		}
+		return
+	}
+
+	// ---------------------------
+	// Status (reports DB reachability itself, so it does not wait for it)
+	// ---------------------------
+	if len(os.Args) > 1 && os.Args[1] == "status" {
+		err := statusCommand(os.Args[2:], instanceCfx, benchDir, dbCfg)
+		if errors.Is(err, errDrift) {
+			os.Exit(3)
+		}
+		if err != nil {
+			log.Fatalf("status failed: %v", err)
+		}
+		return
+	}
+
+	// ---------------------------
+	// Mirrors and bundles (need neither DB nor redis, so they run on build machines too)
+	// ---------------------------
+	if len(os.Args) > 1 && os.Args[1] == "mirrors" {
+		if err := mirrorsCommand(os.Args[2:], benchDir); err != nil {
+			log.Fatalf("mirrors failed: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "bundle" {
+		if err := bundleCommand(os.Args[2:], instanceCfx, benchDir); err != nil {
+			log.Fatalf("bundle failed: %v", err)
+		}
+		return
+	}
 
 	// ---------------------------
 	// Wait for DB
@@ -68,9 +127,41 @@ func main() {
 		}
 	}
 
+	// ---------------------------
+	// One-off commands
+	// ---------------------------
+	if len(os.Args) > 1 {
+		var err error
+		switch os.Args[1] {
+		case "plan":
+			err = sites.Plan(instanceCfx, benchDir)
+		case "backup":
+			err = backupCommand(os.Args[2:], instanceCfx, commonCfg)
+		case "apps":
+			err = appsCommand(os.Args[2:], instanceCfx, benchDir, dbCfg)
+		case "maintenance":
+			err = maintenanceCommand(os.Args[2:], benchDir)
+		case "sites":
+			err = sitesCommand(os.Args[2:], instanceCfx, benchDir, dbCfg)
+		default:
+			err = fmt.Errorf("unknown command %q", os.Args[1])
+		}
+		if err != nil {
+			log.Fatalf("%s failed: %v", os.Args[1], err)
+		}
+		return
+	}
+
 	// ---------------------------
 	// Initialize Bench if not exists
 	// ---------------------------
+	// A bundle import that was interrupted left a bench that cannot run, start over instead
+	if bench.ImportIncomplete(benchDir) {
+		log.Printf("bench directory %s is an incomplete bundle import, removing it", benchDir)
+		if err := os.RemoveAll(benchDir); err != nil {
+			log.Fatalf("failed to remove incomplete bench: %v", err)
+		}
+	}
 	if _, err := os.Stat(benchDir); os.IsNotExist(err) {
 		log.Printf("bench directory %s does not exist, initializing...", benchDir)
 		if err := bench.Initialize(environ.GetBenchName(), instanceCfx.FrappeBranch); err != nil {
@@ -91,16 +182,74 @@ func main() {
 	// Checkout sites for anomalies and missing sites
 	// ---------------------------
 	if err := sites.CheckoutSites(instanceCfx, benchDir, dbCfg.User, dbCfg.Password); err != nil {
+		sites.Summarize()
 		log.Fatalf("sites sync failed: %v", err)
 	}
 
 	// ---------------------------
 	// Update bench and apps after deployment
 	// ---------------------------
-	if err := bench.UpdateApps(benchDir); err != nil {
-		fmt.Printf("[ERROR] Failed to update bench apps: %v", err)
+	// Apps move according to their update policies and the update windows
+	if err := updates.Run(instanceCfx, benchDir, dbCfg, nil, false); err != nil {
+		fmt.Printf("[ERROR] App update failed: %v\n", err)
+	}
+	if err := backup.PruneSafety(); err != nil {
+		fmt.Printf("[ERROR] Failed to prune safety backups: %v\n", err)
+	}
+
+	// ---------------------------
+	// TLS certificates
+	// ---------------------------
+	if deployment == "production" && instanceCfx.TLS != nil {
+		if err := sites.CheckoutTLS(instanceCfx); err != nil {
+			log.Fatalf("tls provisioning failed: %v", err)
+		}
+	}
+
+	// ---------------------------
+	// Nginx and supervisor
+	// ---------------------------
+	if deployment == "production" {
+		if err := supervisor.SetupNginx(instanceCfx, benchDir); err != nil {
+			log.Fatalf("nginx setup failed: %v", err)
+		}
+		if err := supervisor.WriteBenchConf(instanceCfx, commonCfg, benchDir); err != nil {
+			log.Fatalf("supervisor setup failed: %v", err)
+		}
+	}
+
+	// ---------------------------
+	// Scheduled backups
+	// ---------------------------
+	// Background loops read the live config, which hot reload replaces as a whole
+	live := config.NewLive(instanceCfx, commonCfg)
+	if deployment == "production" {
+		go certs.RenewLoop(live)
+	}
+	go backup.ScheduleLoop(live)
+	go updates.WindowLoop(live, benchDir, dbCfg)
+	go bench.MirrorLoop(benchDir)
+
+	// ---------------------------
+	// Hot reload of instance.json and common_site_config.json
+	// ---------------------------
+	if !instanceCfx.DisableHotReload {
+		go reload.Loop(live, benchDir, dbCfg)
+	}
+
+	// ---------------------------
+	// Drift watcher
+	// ---------------------------
+	go watch.Loop(live, benchDir, dbCfg)
+
+	// ---------------------------
+	// Per-site summary
+	// ---------------------------
+	failedSites := sites.Summarize()
+	if failedSites > 0 {
+		fmt.Printf("[ERROR] %d site(s) failed during this run, see the summary above; starting services for the others\n", failedSites)
+		fmt.Println("[ERROR] While the services run, `goftw-entry status` reports the failure from health.json; the exit status 2 only follows when they stop")
 	}
-	sites.MigrateAll(benchDir)
 
 	// ---------------------------
 	// Deployment
@@ -110,6 +259,11 @@ func main() {
 	// Currently, production mode has issues with hitting default nginx welcome page
 	// However, development mode works fine
 	internalDeploy.DeployThroughShell(deployment)
+	// DeployThroughShell returns once supervisord stops, so this status is only seen at shutdown;
+	// the failure is reported from health.json until then
+	if failedSites > 0 {
+		os.Exit(2)
+	}
 	// switch deployment {
 	// case "production":
 	// 	if err := internalDeploy.RunProduction(); err != nil {
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+
+	"goftw/internal/bench"
+	"goftw/internal/sites"
+)
+
+// maintenanceCommand handles `goftw-entry maintenance on|off [site...]`. Without sites it
+// applies to every site of the bench. The scheduler is paused and resumed along with it.
+func maintenanceCommand(args []string, benchDir string) error {
+	if len(args) == 0 || (args[0] != "on" && args[0] != "off") {
+		return fmt.Errorf("usage: maintenance on|off [site...]")
+	}
+	targets := args[1:]
+	if len(targets) == 0 {
+		all, err := bench.ListSites(benchDir)
+		if err != nil {
+			return err
+		}
+		targets = all
+	}
+
+	failed := 0
+	for _, site := range targets {
+		fmt.Printf("[SITES] Maintenance %s for site %s\n", args[0], site)
+		if err := sites.SetMaintenance(site, args[0] == "on"); err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d sites failed", failed, len(targets))
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"goftw/internal/bench"
+	"goftw/internal/environ"
+)
+
+// mirrorsCommand handles `goftw-entry mirrors list` and `goftw-entry mirrors sync [app...]`.
+// sync creates or refreshes the mirrors of the named apps, or of every app of the bench and
+// every existing mirror when none are named.
+func mirrorsCommand(args []string, benchDir string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: mirrors list | mirrors sync [app...]")
+	}
+
+	switch args[0] {
+	case "list":
+		apps, err := bench.MirroredApps()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("[MIRROR] Mirrors in %s\n", environ.GetMirrorPath())
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "APP\tREMOTE")
+		for _, app := range apps {
+			remote, err := bench.MirrorRemote(app)
+			if err != nil {
+				remote = "?"
+			}
+			fmt.Fprintf(w, "%s\t%s\n", app, remote)
+		}
+		return w.Flush()
+
+	case "sync":
+		if failed := bench.SyncMirrors(benchDir, args[1:]...); failed > 0 {
+			return fmt.Errorf("%d mirror(s) failed", failed)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown mirrors command %q", args[0])
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"goftw/internal/config"
+	"goftw/internal/db"
+	"goftw/internal/sites"
+)
+
+// sitesCommand handles `goftw-entry sites abandoned`, `goftw-entry sites drop <site>`
+// and `goftw-entry sites clone <src> <dst>`.
+func sitesCommand(args []string, instanceCfg *config.InstanceConfig, benchDir string, dbCfg db.Config) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: sites abandoned | sites drop <site> | sites clone <src> <dst>")
+	}
+
+	switch args[0] {
+	case "abandoned":
+		abandoned, err := sites.Abandoned()
+		if err != nil {
+			return err
+		}
+		names := make([]string, 0, len(abandoned))
+		for name := range abandoned {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SITE\tABANDONED SINCE\tARCHIVE")
+		for _, name := range names {
+			rec := abandoned[name]
+			fmt.Fprintf(w, "%s\t%s\t%s\n", name, rec.Since.Local().Format("2006-01-02 15:04:05"), rec.ArchiveID)
+		}
+		return w.Flush()
+
+	case "drop":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: sites drop <site>")
+		}
+		return sites.ConfirmDrop(instanceCfg, args[1], dbCfg.Password)
+
+	case "clone":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: sites clone <src> <dst>")
+		}
+		return sites.CloneSite(instanceCfg, args[1], args[2], benchDir, dbCfg.User, dbCfg.Password)
+	}
+	return fmt.Errorf("unknown sites command %q", args[0])
+}
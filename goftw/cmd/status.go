@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"goftw/internal/config"
+	"goftw/internal/db"
+	"goftw/internal/sites"
+	"goftw/internal/utils"
+)
+
+// errDrift is returned by statusCommand when any site differs from instance.json
+var errDrift = errors.New("drift detected")
+
+// statusCommand handles `goftw-entry status [--json]`. It changes nothing and returns
+// errDrift when a site needs attention, so cron jobs can alert on the exit status.
+func statusCommand(args []string, instanceCfg *config.InstanceConfig, benchDir string, dbCfg db.Config) error {
+	asJSON := false
+	for _, arg := range args {
+		switch arg {
+		case "--json":
+			asJSON = true
+		default:
+			return fmt.Errorf("usage: status [--json]")
+		}
+	}
+
+	// Keep stdout clean for the JSON document; warnings go to stderr
+	logs := io.Writer(os.Stdout)
+	if asJSON {
+		logs = os.Stderr
+	}
+	dbErr := db.Ping(dbCfg)
+	statuses, err := sites.Status(instanceCfg, benchDir, dbErr == nil, logs)
+	if err != nil {
+		return err
+	}
+
+	lastRun, err := sites.LoadLastRun()
+	if err != nil {
+		return err
+	}
+
+	drift := dbErr != nil || (lastRun != nil && lastRun.Failed > 0)
+	for _, st := range statuses {
+		drift = drift || st.Drifted()
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			DBReachable bool               `json:"db_reachable"`
+			Drift       bool               `json:"drift"`
+			LastRun     *sites.LastRun     `json:"last_run"`
+			Sites       []sites.SiteStatus `json:"sites"`
+		}{dbErr == nil, drift, lastRun, statuses}); err != nil {
+			return err
+		}
+	} else {
+		if dbErr != nil {
+			fmt.Printf("Database %s:%s is NOT reachable: %v\n", dbCfg.Host, dbCfg.Port, dbErr)
+		} else {
+			fmt.Printf("Database %s:%s is reachable\n", dbCfg.Host, dbCfg.Port)
+		}
+		switch {
+		case lastRun == nil:
+			fmt.Println("No reconciliation run recorded yet")
+		case lastRun.Failed > 0:
+			fmt.Printf("Last run at %s FAILED for %d site(s): %s (see %s)\n", lastRun.Time.Format(time.RFC3339),
+				lastRun.Failed, strings.Join(lastRun.FailedSites, ", "), lastRun.Report)
+		default:
+			fmt.Printf("Last run at %s succeeded for every site\n", lastRun.Time.Format(time.RFC3339))
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SITE\tDECLARED\tEXISTS\tMISSING APPS\tEXTRA APPS\tPENDING MIGRATIONS\tSCHEDULER\tMAINTENANCE\tWOULD DROP\tERRORS")
+		for _, st := range statuses {
+			pending := strings.Join(st.PendingMigrations, ",")
+			if !st.MigrationsKnown {
+				pending = "unknown"
+			} else if pending == "" {
+				pending = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", st.Site, utils.YesNo(st.Declared), utils.YesNo(st.Exists),
+				listOrDash(st.MissingApps), listOrDash(st.ExtraApps), pending, orDash(st.Scheduler),
+				utils.YesNo(st.MaintenanceMode), utils.YesNo(st.WouldDrop), listOrDash(st.Errors))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if drift {
+		return errDrift
+	}
+	return nil
+}
+
+func listOrDash(items []string) string {
+	if len(items) == 0 {
+		return "-"
+	}
+	return strings.Join(items, "; ")
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+
+	"goftw/internal/backup"
+	"goftw/internal/config"
+	"goftw/internal/db"
+	"goftw/internal/sites"
+)
+
+// workerCommand runs the per-site jobs that the entrypoint hands to worker processes.
+// It reports whether args named a worker command.
+func workerCommand(args []string, instanceCfg *config.InstanceConfig, benchDir string, dbCfg db.Config) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+	switch args[0] {
+	case "checkout-site":
+		if len(args) != 2 {
+			return true, fmt.Errorf("usage: checkout-site <site>")
+		}
+		return true, sites.CheckoutSiteByName(instanceCfg, args[1], benchDir, dbCfg.User, dbCfg.Password)
+	case "safety-backup":
+		if len(args) != 3 {
+			return true, fmt.Errorf("usage: safety-backup <step> <site>")
+		}
+		_, err := backup.Safety(args[2], args[1], false)
+		return true, err
+	case "migrate-site":
+		if len(args) != 2 {
+			return true, fmt.Errorf("usage: migrate-site <site>")
+		}
+		return true, sites.Migrate(args[1])
+	}
+	return false, nil
+}
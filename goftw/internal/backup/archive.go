@@ -0,0 +1,32 @@
+package backup
+
+import (
+	"fmt"
+
+	"goftw/internal/config"
+)
+
+// ArchiveKind tags the full backups taken of abandoned sites. Archives are never pruned.
+const ArchiveKind = "archive"
+
+// Archive takes a full backup of an abandoned site and, when object storage is configured,
+// uploads it under archive/<site>/ so site retention never touches it.
+func Archive(site string, storage *config.ObjectStorageConfig) (*Entry, error) {
+	fmt.Printf("[BACKUP] Archiving abandoned site %s\n", site)
+	entry, err := Run(site, true, ArchiveKind)
+	if err != nil {
+		return nil, fmt.Errorf("archive of site %s failed: %v", site, err)
+	}
+	if storage != nil {
+		target := config.InstanceSite{SiteName: site, Backup: config.BackupConfig{RemotePrefix: "archive/" + site}}
+		if err := UploadRemote(*entry, target, storage); err != nil {
+			return entry, fmt.Errorf("upload of archive %s failed: %v", entry.ID, err)
+		}
+	}
+	return entry, nil
+}
+
+// IsArchive reports whether a backup is the archive of an abandoned site
+func IsArchive(e Entry) bool {
+	return e.Kind == ArchiveKind
+}
@@ -0,0 +1,174 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"goftw/internal/bench"
+	"goftw/internal/config"
+	"goftw/internal/environ"
+	"goftw/internal/utils"
+)
+
+const (
+	// setLayout names backup sets by their creation time down to the nanosecond
+	setLayout = "20060102T150405.000000000Z"
+	// setParseLayout reads set names; time.Parse accepts fractional seconds after the
+	// seconds field, so it also reads the sets named without them by earlier versions
+	setParseLayout = "20060102T150405Z"
+)
+
+// Run takes a backup of a site with `bench backup`, then records its files,
+// sizes and checksums in the catalog.
+func Run(site string, withFiles bool, kind string) (*Entry, error) {
+	now, dir, err := newSetDir(site)
+	if err != nil {
+		return nil, err
+	}
+	// A set that is not in the catalog is never pruned, so a failed backup removes its files
+	recorded := false
+	defer func() {
+		if !recorded {
+			_ = os.RemoveAll(dir)
+		}
+	}()
+
+	fmt.Printf("[BACKUP] Backing up site %s to %s\n", site, dir)
+	args := []string{"--site", site, "backup", "--backup-path", dir}
+	if withFiles {
+		args = append(args, "--with-files")
+	}
+	if err := bench.RunInBenchPrintIO(args...); err != nil {
+		fmt.Printf("[ERROR] Backup of site %s failed: %v\n", site, err)
+		return nil, err
+	}
+
+	entry := Entry{
+		ID:        site + "/" + filepath.Base(dir),
+		Site:      site,
+		Kind:      kind,
+		CreatedAt: now,
+		Dir:       dir,
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		file, err := describe(path)
+		if err != nil {
+			fmt.Printf("[ERROR] Failed to checksum backup file %s: %v\n", path, err)
+			return nil, err
+		}
+		entry.Files = append(entry.Files, file)
+		entry.Size += file.Size
+	}
+	if len(entry.Files) == 0 {
+		return nil, fmt.Errorf("bench backup produced no files in %s", dir)
+	}
+
+	// Record the installed apps so a restore can check them against the declared ones
+	if out, err := bench.RunInBenchSwallowIO("--site", site, "list-apps"); err == nil {
+		entry.Apps = utils.ExtractAppNames(utils.ParseAppList(out))
+	} else {
+		fmt.Printf("[WARN] Could not list apps of site %s for backup manifest: %v\n", site, err)
+	}
+	if err := writeManifest(entry); err != nil {
+		fmt.Printf("[ERROR] Failed to write backup manifest: %v\n", err)
+		return nil, err
+	}
+
+	if err := record(entry); err != nil {
+		fmt.Printf("[ERROR] Failed to record backup %s in catalog: %v\n", entry.ID, err)
+		return nil, err
+	}
+	recorded = true
+	fmt.Printf("[BACKUP] Site %s backed up: %d file(s), %d bytes\n", site, len(entry.Files), entry.Size)
+	return &entry, nil
+}
+
+// newSetDir creates the directory of a new backup set, named after its creation time.
+// Two backups of a site within the same second get distinct directories and catalog IDs.
+func newSetDir(site string) (time.Time, string, error) {
+	parent := filepath.Join(environ.GetBackupPath(), site)
+	if err := os.MkdirAll(parent, 0750); err != nil {
+		return time.Time{}, "", fmt.Errorf("failed to create backup directory %s: %v", parent, err)
+	}
+	for {
+		now := time.Now().UTC()
+		dir := filepath.Join(parent, now.Format(setLayout))
+		err := os.Mkdir(dir, 0750)
+		if err == nil {
+			return now, dir, nil
+		}
+		if !os.IsExist(err) {
+			return time.Time{}, "", fmt.Errorf("failed to create backup directory %s: %v", dir, err)
+		}
+	}
+}
+
+// RunAndPrune backs up a site according to its declared options, uploads the set to
+// object storage when configured, and applies the retention policy locally and remotely.
+func RunAndPrune(site config.InstanceSite, instanceCfg *config.InstanceConfig, commonCfg *config.CommonConfig, kind string) (*Entry, error) {
+	entry, err := Run(site.SiteName, !site.Backup.SkipFiles, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	retention := RetentionFor(site, commonCfg)
+	if instanceCfg.ObjectStorage != nil {
+		if err := UploadRemote(*entry, site, instanceCfg.ObjectStorage); err != nil {
+			fmt.Printf("[ERROR] Failed to upload backup %s: %v\n", entry.ID, err)
+			return entry, err
+		}
+		if err := PruneRemote(site, instanceCfg.ObjectStorage, retention); err != nil {
+			fmt.Printf("[ERROR] Failed to prune remote backups for site %s: %v\n", site.SiteName, err)
+			return entry, err
+		}
+	}
+
+	if err := Prune(site.SiteName, retention); err != nil {
+		fmt.Printf("[ERROR] Failed to prune backups for site %s: %v\n", site.SiteName, err)
+		return entry, err
+	}
+	return entry, nil
+}
+
+// Verify recomputes the checksums of a backup set and compares them with the catalog.
+func Verify(entry Entry) error {
+	for _, f := range entry.Files {
+		actual, err := describe(f.Path)
+		if err != nil {
+			return err
+		}
+		if actual.SHA256 != f.SHA256 {
+			return fmt.Errorf("checksum mismatch for %s", f.Path)
+		}
+	}
+	return nil
+}
+
+// describe returns the size and SHA-256 checksum of a file
+func describe(path string) (File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return File{}, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return File{}, err
+	}
+	return File{Path: path, Size: size, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+func restoreDir() string {
+	return filepath.Join(environ.GetBackupPath(), ".restore")
+}
@@ -0,0 +1,25 @@
+package backup
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetNames(t *testing.T) {
+	first := time.Date(2024, time.January, 15, 2, 0, 0, 100, time.UTC)
+	second := first.Add(time.Millisecond)
+	if first.Format(setLayout) == second.Format(setLayout) {
+		t.Errorf("two backups within one second share the set name %s", first.Format(setLayout))
+	}
+
+	for _, name := range []string{second.Format(setLayout), "20240115T020000Z"} {
+		created, err := time.Parse(setParseLayout, name)
+		if err != nil {
+			t.Errorf("set %s: %v", name, err)
+			continue
+		}
+		if created.Truncate(time.Second) != first.Truncate(time.Second) {
+			t.Errorf("set %s: created at %v, want %v", name, created, first.Truncate(time.Second))
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package backup
+
+import (
+	"sort"
+	"time"
+
+	"goftw/internal/state"
+)
+
+const catalogState = "backups.json"
+
+// File is one file of a backup set
+type File struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Entry is one backup set recorded in the catalog
+type Entry struct {
+	ID        string    `json:"id"`
+	Site      string    `json:"site"`
+	Kind      string    `json:"kind"` // "scheduled", "manual", "archive" or "safety-<step>"
+	CreatedAt time.Time `json:"created_at"`
+	Dir       string    `json:"dir"`
+	Apps      []string  `json:"apps"` // apps installed on the site when it was backed up
+	Files     []File    `json:"files"`
+	Size      int64     `json:"size"`
+	Remote    string    `json:"remote,omitempty"` // object storage prefix the set was uploaded to
+}
+
+// List returns the catalogued backups of a site, newest first. An empty site lists every backup.
+func List(site string) ([]Entry, error) {
+	unlock, err := state.Lock(catalogState)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	entries, err := loadCatalog()
+	if err != nil {
+		return nil, err
+	}
+	var out []Entry
+	for _, e := range entries {
+		if site == "" || e.Site == site {
+			out = append(out, e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+// record adds an entry to the catalog
+func record(entry Entry) error {
+	return updateCatalog(func(entries []Entry) []Entry {
+		return append(entries, entry)
+	})
+}
+
+// setRemote records where a backup set was uploaded
+func setRemote(id, remote string) error {
+	return updateCatalog(func(entries []Entry) []Entry {
+		for i := range entries {
+			if entries[i].ID == id {
+				entries[i].Remote = remote
+			}
+		}
+		return entries
+	})
+}
+
+// forget removes entries from the catalog by ID
+func forget(ids map[string]bool) error {
+	return updateCatalog(func(entries []Entry) []Entry {
+		kept := entries[:0]
+		for _, e := range entries {
+			if !ids[e.ID] {
+				kept = append(kept, e)
+			}
+		}
+		return kept
+	})
+}
+
+func updateCatalog(update func([]Entry) []Entry) error {
+	unlock, err := state.Lock(catalogState)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := loadCatalog()
+	if err != nil {
+		return err
+	}
+	return state.Save(catalogState, update(entries))
+}
+
+func loadCatalog() ([]Entry, error) {
+	var entries []Entry
+	if err := state.Load(catalogState, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
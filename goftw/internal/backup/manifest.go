@@ -0,0 +1,129 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"goftw/internal/config"
+)
+
+// ManifestName is the file describing a backup set, stored next to the files it lists
+const ManifestName = "manifest.json"
+
+// Manifest travels with a backup set so it can be verified wherever it is restored
+type Manifest struct {
+	Site      string    `json:"site"`
+	CreatedAt time.Time `json:"created_at"`
+	Apps      []string  `json:"apps"`
+	Files     []File    `json:"files"` // paths are relative to the set directory
+}
+
+// writeManifest writes the manifest of a catalogued backup set into its directory
+func writeManifest(entry Entry) error {
+	m := Manifest{Site: entry.Site, CreatedAt: entry.CreatedAt, Apps: entry.Apps}
+	for _, f := range entry.Files {
+		m.Files = append(m.Files, File{Path: filepath.Base(f.Path), Size: f.Size, SHA256: f.SHA256})
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(entry.Dir, ManifestName), data, 0640)
+}
+
+// VerifyDir reads the manifest of a backup set directory and checks every file against its checksum.
+func VerifyDir(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestName))
+	if err != nil {
+		return nil, fmt.Errorf("backup set %s has no readable %s: %v", dir, ManifestName, err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest in %s: %v", dir, err)
+	}
+	for _, f := range m.Files {
+		actual, err := describe(filepath.Join(dir, f.Path))
+		if err != nil {
+			return nil, fmt.Errorf("backup file %s: %v", f.Path, err)
+		}
+		if actual.SHA256 != f.SHA256 {
+			return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", f.Path, f.SHA256, actual.SHA256)
+		}
+	}
+	fmt.Printf("[BACKUP] Verified %d file(s) in %s\n", len(m.Files), dir)
+	return &m, nil
+}
+
+// Fetch resolves a restore source to a local backup set directory. The source is either
+// a local directory, a catalog ID such as "site/20250101T020000Z", or "s3:<key prefix>"
+// naming a set in object storage, which is downloaded first.
+func Fetch(source string, storage *config.ObjectStorageConfig) (string, error) {
+	if key, ok := strings.CutPrefix(source, "s3:"); ok {
+		if storage == nil {
+			return "", fmt.Errorf("restore source %s needs object_storage in instance.json", source)
+		}
+		return download(strings.TrimSuffix(key, "/")+"/", storage)
+	}
+
+	if info, err := os.Stat(source); err == nil && info.IsDir() {
+		return source, nil
+	}
+	entries, err := List("")
+	if err != nil {
+		return "", err
+	}
+	for _, e := range entries {
+		if e.ID == source {
+			return e.Dir, nil
+		}
+	}
+	return "", fmt.Errorf("restore source %s is neither a backup directory nor a catalogued backup", source)
+}
+
+// download copies the backup set under prefix into a local restore directory. The
+// manifest comes first, and every file it lists is checked against its checksum as it
+// is downloaded, so a corrupt object never reaches a restore.
+func download(prefix string, storage *config.ObjectStorageConfig) (string, error) {
+	client, err := NewRemoteClient(storage)
+	if err != nil {
+		return "", err
+	}
+	objects, err := client.List(prefix)
+	if err != nil {
+		return "", err
+	}
+	if len(objects) == 0 {
+		return "", fmt.Errorf("no objects found under s3://%s/%s", storage.Bucket, prefix)
+	}
+
+	dir := filepath.Join(restoreDir(), strings.ReplaceAll(strings.TrimSuffix(prefix, "/"), "/", "_"))
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return "", err
+	}
+	fmt.Printf("[BACKUP] Downloading s3://%s/%s%s\n", storage.Bucket, prefix, ManifestName)
+	if err := client.Download(prefix+ManifestName, filepath.Join(dir, ManifestName), ""); err != nil {
+		return "", fmt.Errorf("backup set s3://%s/%s has no readable %s: %v", storage.Bucket, prefix, ManifestName, err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, ManifestName))
+	if err != nil {
+		return "", err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return "", fmt.Errorf("invalid manifest in s3://%s/%s: %v", storage.Bucket, prefix, err)
+	}
+	for _, f := range m.Files {
+		if f.Path != filepath.Base(f.Path) {
+			return "", fmt.Errorf("invalid file %q in manifest of s3://%s/%s", f.Path, storage.Bucket, prefix)
+		}
+		fmt.Printf("[BACKUP] Downloading s3://%s/%s%s\n", storage.Bucket, prefix, f.Path)
+		if err := client.Download(prefix+f.Path, filepath.Join(dir, f.Path), f.SHA256); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
@@ -0,0 +1,107 @@
+package backup
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"goftw/internal/config"
+	"goftw/internal/s3"
+	"goftw/internal/secrets"
+)
+
+// NewRemoteClient returns a client for the configured object storage bucket
+func NewRemoteClient(cfg *config.ObjectStorageConfig) (*s3.Client, error) {
+	accessRef, secretRef := cfg.AccessKey, cfg.SecretKey
+	if accessRef == "" {
+		accessRef = "env:AWS_ACCESS_KEY_ID"
+	}
+	if secretRef == "" {
+		secretRef = "env:AWS_SECRET_ACCESS_KEY"
+	}
+	accessKey, err := secrets.Resolve(accessRef)
+	if err != nil {
+		return nil, fmt.Errorf("object storage access key: %v", err)
+	}
+	secretKey, err := secrets.Resolve(secretRef)
+	if err != nil {
+		return nil, fmt.Errorf("object storage secret key: %v", err)
+	}
+	return s3.New(cfg.Endpoint, cfg.Region, cfg.Bucket, accessKey, secretKey, cfg.PathStyle)
+}
+
+// RemotePrefix returns the key prefix under which a site's backup sets are stored
+func RemotePrefix(site config.InstanceSite, cfg *config.ObjectStorageConfig) string {
+	sitePrefix := site.Backup.RemotePrefix
+	if sitePrefix == "" {
+		sitePrefix = site.SiteName
+	}
+	return strings.TrimPrefix(path.Join(cfg.Prefix, sitePrefix), "/") + "/"
+}
+
+// UploadRemote uploads every file of a backup set to <prefix>/<set>/ and records the location in the catalog.
+func UploadRemote(entry Entry, site config.InstanceSite, cfg *config.ObjectStorageConfig) error {
+	client, err := NewRemoteClient(cfg)
+	if err != nil {
+		return err
+	}
+	remote := RemotePrefix(site, cfg) + filepath.Base(entry.Dir) + "/"
+	manifest, err := describe(filepath.Join(entry.Dir, ManifestName))
+	if err != nil {
+		return err
+	}
+	for _, f := range append(entry.Files, manifest) {
+		key := remote + filepath.Base(f.Path)
+		fmt.Printf("[BACKUP] Uploading %s to s3://%s/%s\n", filepath.Base(f.Path), cfg.Bucket, key)
+		if err := client.Upload(key, f.Path, f.SHA256); err != nil {
+			return err
+		}
+	}
+	return setRemote(entry.ID, remote)
+}
+
+// PruneRemote deletes remote backup sets of a site that fall outside the retention policy,
+// mirroring what Prune does locally.
+func PruneRemote(site config.InstanceSite, cfg *config.ObjectStorageConfig, r Retention) error {
+	client, err := NewRemoteClient(cfg)
+	if err != nil {
+		return err
+	}
+	prefix := RemotePrefix(site, cfg)
+	objects, err := client.List(prefix)
+	if err != nil {
+		return err
+	}
+
+	// Group objects into sets by their <timestamp>/ directory
+	sets := map[string][]string{}
+	var entries []Entry
+	for _, obj := range objects {
+		set, _, ok := strings.Cut(strings.TrimPrefix(obj.Key, prefix), "/")
+		if !ok {
+			continue
+		}
+		created, err := time.Parse(setParseLayout, set)
+		if err != nil {
+			continue // not a goftw backup set
+		}
+		if _, seen := sets[set]; !seen {
+			entries = append(entries, Entry{ID: set, CreatedAt: created})
+		}
+		sets[set] = append(sets[set], obj.Key)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].CreatedAt.After(entries[j].CreatedAt) })
+
+	for _, e := range r.Expired(entries, time.Now()) {
+		fmt.Printf("[BACKUP] Pruning remote backup s3://%s/%s%s/\n", cfg.Bucket, prefix, e.ID)
+		for _, key := range sets[e.ID] {
+			if err := client.Delete(key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"goftw/internal/config"
+)
+
+// Retention decides which backups of a site are kept
+type Retention struct {
+	KeepLast int           // keep at most this many backups, 0 for no limit
+	MaxAge   time.Duration // drop backups older than this, 0 for no limit
+}
+
+// RetentionFor returns a site's retention policy, falling back to backup_limit from common_site_config.json.
+func RetentionFor(site config.InstanceSite, commonCfg *config.CommonConfig) Retention {
+	r := Retention{
+		KeepLast: site.Backup.KeepLast,
+		MaxAge:   time.Duration(site.Backup.MaxAgeDays) * 24 * time.Hour,
+	}
+	if r.KeepLast == 0 && commonCfg != nil {
+		r.KeepLast = commonCfg.BackupLimit
+	}
+	return r
+}
+
+// Expired returns the backups a retention policy drops, given entries sorted newest first.
+func (r Retention) Expired(entries []Entry, now time.Time) []Entry {
+	var expired []Entry
+	for i, e := range entries {
+		if (r.KeepLast > 0 && i >= r.KeepLast) || (r.MaxAge > 0 && now.Sub(e.CreatedAt) > r.MaxAge) {
+			expired = append(expired, e)
+		}
+	}
+	return expired
+}
+
+// Prune deletes the local backups of a site that fall outside the retention policy.
+// Safety backups follow their own retention, see PruneSafety; archives are kept.
+func Prune(site string, r Retention) error {
+	entries, err := List(site)
+	if err != nil {
+		return err
+	}
+	var regular []Entry
+	for _, e := range entries {
+		if !IsSafety(e) && !IsArchive(e) {
+			regular = append(regular, e)
+		}
+	}
+	return remove(r.Expired(regular, time.Now()))
+}
+
+// remove deletes backup sets from disk and from the catalog
+func remove(expired []Entry) error {
+	removed := map[string]bool{}
+	for _, e := range expired {
+		fmt.Printf("[BACKUP] Pruning backup %s\n", e.ID)
+		if err := os.RemoveAll(e.Dir); err != nil {
+			fmt.Printf("[ERROR] Failed to remove backup %s: %v\n", e.Dir, err)
+			continue
+		}
+		removed[e.ID] = true
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	return forget(removed)
+}
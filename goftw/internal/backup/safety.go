@@ -0,0 +1,70 @@
+package backup
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"goftw/internal/config"
+)
+
+// Safety backups are tagged with the step they protect, e.g. "safety-drop"
+const safetyKindPrefix = "safety-"
+
+var (
+	// Replaced by hot reload while backups may run in other goroutines
+	safetyMu     sync.RWMutex
+	safetyPolicy config.SafetyBackupConfig
+)
+
+// SetSafetyPolicy sets how safety backups are taken and kept, as declared in instance.json.
+func SetSafetyPolicy(cfg config.SafetyBackupConfig) {
+	safetyMu.Lock()
+	defer safetyMu.Unlock()
+	safetyPolicy = cfg
+}
+
+func currentSafetyPolicy() config.SafetyBackupConfig {
+	safetyMu.RLock()
+	defer safetyMu.RUnlock()
+	return safetyPolicy
+}
+
+// Safety takes a backup of a site before a destructive step (drop, uninstall, migrate).
+// Callers must not proceed with the step if it fails. The entry is nil when safety backups are disabled.
+func Safety(site, step string, withFiles bool) (*Entry, error) {
+	if currentSafetyPolicy().Disabled {
+		return nil, nil
+	}
+	fmt.Printf("[BACKUP] Taking safety backup of site %s before %s\n", site, step)
+	entry, err := Run(site, withFiles, safetyKindPrefix+step)
+	if err != nil {
+		return nil, fmt.Errorf("safety backup before %s failed: %v", step, err)
+	}
+	return entry, nil
+}
+
+// PruneSafety deletes safety backups older than the configured retention period.
+func PruneSafety() error {
+	days := currentSafetyPolicy().RetentionDays
+	if days <= 0 {
+		days = 7
+	}
+	entries, err := List("")
+	if err != nil {
+		return err
+	}
+	var safety []Entry
+	for _, e := range entries {
+		if IsSafety(e) {
+			safety = append(safety, e)
+		}
+	}
+	return remove(Retention{MaxAge: time.Duration(days) * 24 * time.Hour}.Expired(safety, time.Now()))
+}
+
+// IsSafety reports whether a backup was taken automatically before a destructive step
+func IsSafety(e Entry) bool {
+	return strings.HasPrefix(e.Kind, safetyKindPrefix)
+}
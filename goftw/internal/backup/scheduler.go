@@ -0,0 +1,74 @@
+package backup
+
+import (
+	"fmt"
+	"time"
+
+	"goftw/internal/config"
+	"goftw/internal/cron"
+)
+
+// maxCatchUp bounds how far back ScheduleLoop looks for minutes it missed
+const maxCatchUp = 24 * time.Hour
+
+// ScheduleLoop runs each site's backups on its cron schedule. Schedules are read from the
+// live config on every tick, so hot-reloaded schedules take effect at once. Backups run
+// inline, so every minute since the previous tick is checked: a schedule that fired while
+// another backup was running still runs, once, when that backup finishes. It never
+// returns and is meant to run in a goroutine next to the deployed services.
+func ScheduleLoop(live *config.Live) {
+	last := time.Now().Truncate(time.Minute)
+	for {
+		// Wake up at the start of every minute
+		now := time.Now()
+		time.Sleep(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+		tick := time.Now().Truncate(time.Minute)
+		if tick.Sub(last) > maxCatchUp {
+			last = tick.Add(-maxCatchUp)
+		}
+		missed := due(last, tick)
+		last = tick
+
+		instanceCfg, commonCfg := live.Instance(), live.Common()
+		for _, site := range instanceCfg.InstanceSites {
+			if site.Backup.Schedule == "" {
+				continue
+			}
+			s, err := cron.Parse(site.Backup.Schedule)
+			if err != nil {
+				fmt.Printf("[ERROR] Invalid backup schedule for site %s: %v\n", site.SiteName, err)
+				continue
+			}
+			if !matchesAny(s, missed) {
+				continue
+			}
+			if !s.Matches(tick) {
+				fmt.Printf("[BACKUP] Catching up on a scheduled backup of site %s missed while busy\n", site.SiteName)
+			}
+			if _, err := RunAndPrune(site, instanceCfg, commonCfg, "scheduled"); err != nil {
+				fmt.Printf("[ERROR] Scheduled backup of site %s failed: %v\n", site.SiteName, err)
+			}
+		}
+	}
+}
+
+// due lists the minutes after last up to and including tick
+func due(last, tick time.Time) []time.Time {
+	var minutes []time.Time
+	for m := last.Add(time.Minute); !m.After(tick); m = m.Add(time.Minute) {
+		minutes = append(minutes, m)
+	}
+	if len(minutes) == 0 {
+		minutes = append(minutes, tick)
+	}
+	return minutes
+}
+
+func matchesAny(s *cron.Schedule, minutes []time.Time) bool {
+	for _, m := range minutes {
+		if s.Matches(m) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package backup
+
+import (
+	"testing"
+	"time"
+
+	"goftw/internal/cron"
+)
+
+func TestDueCatchesUpMissedMinutes(t *testing.T) {
+	last := time.Date(2024, time.January, 15, 1, 58, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		tick     time.Time
+		expr     string
+		want     int
+		wantFire bool
+	}{
+		{"next minute", last.Add(time.Minute), "59 1 * * *", 1, true},
+		{"schedule fired during a long backup", last.Add(5 * time.Minute), "0 2 * * *", 5, true},
+		{"schedule not due yet", last.Add(5 * time.Minute), "10 2 * * *", 5, false},
+		{"clock went back", last.Add(-time.Minute), "57 1 * * *", 1, true},
+	}
+	for _, tt := range tests {
+		minutes := due(last, tt.tick)
+		if len(minutes) != tt.want {
+			t.Errorf("%s: %d minutes due, want %d", tt.name, len(minutes), tt.want)
+		}
+		s, err := cron.Parse(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := matchesAny(s, minutes); got != tt.wantFire {
+			t.Errorf("%s: %q fires = %v, want %v", tt.name, tt.expr, got, tt.wantFire)
+		}
+	}
+}
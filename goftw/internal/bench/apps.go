@@ -2,46 +2,264 @@ package bench
 
 import (
 	"fmt"
-	"goftw/internal/sudo"
+	"goftw/internal/environ"
+	"goftw/internal/state"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
-// GetApp fetches an app from branch
+// GetApp fetches an app from branch, cloning it from its local mirror when mirrors are enabled
 func GetApp(app, branch string) error {
 	fmt.Printf("[APPS] Fetching app: %s from branch: %s\n", app, branch)
-	_, err := RunInBenchSwallowIO("get-app", "--branch", branch, app)
-	return err
+	return WithSharedLock(func() error {
+		// Another worker may have fetched it while we waited for the lock
+		if _, err := os.Stat(environ.GetBenchAppPath(app)); err == nil {
+			return nil
+		}
+		if ref, ok := LocalAppRef(app); ok {
+			return linkLocalApp(app, ref)
+		}
+		env, err := mirrorEnv(app)
+		if err != nil {
+			return err
+		}
+		source := app
+		if env != nil {
+			// bench clones the real URL, which git rewrites to the mirror
+			source = AppRemote(app)
+		}
+		if _, err := runInBench(env, "get-app", "--branch", branch, source); err != nil {
+			return err
+		}
+		// A pinned app must not start on whatever the branch head is today
+		if ref, ok := pinnedRef(app); ok {
+			if u := PinApp(environ.GetBenchPath(), app, ref); u.Error != "" {
+				return fmt.Errorf("failed to pin app %s to %s: %s", app, ref, u.Error)
+			}
+		}
+		return nil
+	})
+}
+
+// AppUpdate describes what updating one app did
+type AppUpdate struct {
+	App     string   `json:"app"`
+	Branch  string   `json:"branch,omitempty"`
+	From    string   `json:"from,omitempty"`
+	To      string   `json:"to,omitempty"`
+	Commits []string `json:"commits,omitempty"` // "<short hash> <subject>", newest first
+	Reset   bool     `json:"reset,omitempty"`   // local commits were discarded (non fast-forward)
+	Skipped string   `json:"skipped,omitempty"` // why the app was left alone
+	Error   string   `json:"error,omitempty"`
 }
 
-func UpdateApps(benchDir string) error {
+// Changed reports whether the app moved to another commit
+func (u AppUpdate) Changed() bool {
+	return u.From != u.To && u.To != ""
+}
+
+// keepAppUpdateReports is how many app update reports are kept in the state directory
+const keepAppUpdateReports = 50
+
+// UpdateApps fetches and fast-forwards every app of the bench except those in skip (app ->
+// reason), and checks out apps in pins (app -> ref) at their ref instead. It prints what
+// changed and writes the report to the state directory. Apps that cannot be updated safely
+// are left where they are and reported. Callers hold WithSharedLock.
+func UpdateApps(benchDir string, allowNonFastForward bool, skip, pins map[string]string) ([]AppUpdate, error) {
+	if skip == nil {
+		skip = map[string]string{}
+	}
 	appNames, err := ListApps(benchDir)
 	if err != nil {
 		fmt.Printf("[ERROR] Failed to list apps for update: %v\n", err)
-		return err
+		return nil, err
 	}
 
+	var updates []AppUpdate
 	for _, app := range appNames {
-		fmt.Printf("[BENCH] Syncing app: %s with remote ...", app)
-		if err := UpdateApp(benchDir, app); err != nil {
-			fmt.Printf("[ERROR] Failed to update app %s: %v\n", app, err)
-
+		if IsLocalApp(benchDir, app) {
+			skip[app] = "local development app"
+		} else if ref, ok := pins[app]; ok {
+			// PinApp prints its own outcome, failures included
+			updates = append(updates, PinApp(benchDir, app, ref))
+			continue
+		}
+		if reason, ok := skip[app]; ok {
+			updates = append(updates, AppUpdate{App: app, Skipped: reason})
+			fmt.Printf("[APPS] Not updating app %s: %s\n", app, reason)
+			continue
 		}
+		fmt.Printf("[BENCH] Syncing app: %s with remote ...\n", app)
+		update := UpdateApp(benchDir, app, allowNonFastForward)
+		updates = append(updates, update)
+		printUpdate(update)
+	}
+
+	name := "reports/app-update-" + time.Now().UTC().Format("20060102T150405Z") + ".json"
+	if err := state.Save(name, updates); err != nil {
+		fmt.Printf("[ERROR] Failed to write app update report: %v\n", err)
+	}
+	// Update windows write a report every time they open, so only the latest ones are kept
+	if err := state.Prune("reports/app-update-", keepAppUpdateReports); err != nil {
+		fmt.Printf("[ERROR] Failed to prune app update reports: %v\n", err)
 	}
-	return nil
+	return updates, nil
 }
 
-// UpdateApp updates an app by pulling the latest changes from its git repository
-func UpdateApp(benchDir, app string) error {
+// UpdateApp fetches an app's branch and fast-forwards to it. Dirty working trees and
+// detached checkouts are never touched; local commits that would be lost block the
+// update unless allowNonFastForward is set.
+func UpdateApp(benchDir, app string, allowNonFastForward bool) AppUpdate {
 	appPath := benchDir + "/apps/" + app
+	update := AppUpdate{App: app}
+	fail := func(err error) AppUpdate {
+		update.Error = err.Error()
+		return update
+	}
 
 	// Check if app exists
 	if _, err := os.Stat(appPath); os.IsNotExist(err) {
-		// App doesn’t exist, fallback to get-app
-		fmt.Printf("[APPS] Could not update missing app: %s", app)
-		return fmt.Errorf("app %s does not exist at path %s", app, appPath)
+		return fail(fmt.Errorf("app %s does not exist at path %s", app, appPath))
+	}
+
+	branch, err := git(appPath, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return fail(err)
 	}
+	if branch == "HEAD" {
+		update.Skipped = "detached HEAD, not on a branch"
+		return update
+	}
+	update.Branch = branch
 
-	// App exists: go into its dir and pull latest
-	fmt.Printf("[APPS] Updating existing app: %s\n", app)
-	return sudo.RunInBenchPrintIO("git", "-C", appPath, "pull")
+	if dirty, err := git(appPath, "status", "--porcelain", "--untracked-files=no"); err != nil {
+		return fail(err)
+	} else if dirty != "" {
+		update.Skipped = "working tree has uncommitted changes"
+		return update
+	}
+
+	// The upstream branch may be named differently from the local one; the explicit refspec
+	// updates the remote-tracking ref compared against below even for single-branch clones
+	remote, ref, err := upstreamRef(appPath, branch)
+	if err != nil {
+		return fail(err)
+	}
+	remoteBranch := strings.TrimPrefix(ref, remote+"/")
+	if _, err := git(appPath, "fetch", remote, "+refs/heads/"+remoteBranch+":refs/remotes/"+ref); err != nil {
+		return fail(err)
+	}
+
+	if update.From, err = git(appPath, "rev-parse", "HEAD"); err != nil {
+		return fail(err)
+	}
+	target, err := git(appPath, "rev-parse", ref)
+	if err != nil {
+		return fail(err)
+	}
+	if target == update.From {
+		update.To = target
+		return update
+	}
+	if update.Commits, err = changelog(appPath, update.From, target); err != nil {
+		return fail(err)
+	}
+
+	// HEAD must be an ancestor of the remote branch, otherwise local commits would be lost
+	if _, err := git(appPath, "merge-base", "--is-ancestor", update.From, target); err == nil {
+		if _, err := git(appPath, "merge", "--ff-only", target); err != nil {
+			return fail(err)
+		}
+	} else if allowNonFastForward {
+		local, _ := changelog(appPath, target, update.From)
+		fmt.Printf("[WARN] Discarding %d local commit(s) of app %s\n", len(local), app)
+		if _, err := git(appPath, "reset", "--hard", target); err != nil {
+			return fail(err)
+		}
+		update.Reset = true
+	} else {
+		update.Skipped = fmt.Sprintf("%s has local commits not on %s (non fast-forward)", branch, ref)
+		return update
+	}
+	update.To = target
+	return update
+}
+
+// PinApp checks out an app at ref (a commit, tag or branch), fetching it if it is not known
+// locally. The app is left on a detached HEAD, which automatic updates never move.
+func PinApp(benchDir, app, ref string) AppUpdate {
+	appPath := filepath.Join(benchDir, "apps", app)
+	update := AppUpdate{App: app, Branch: ref}
+	fail := func(err error) AppUpdate {
+		update.Error = err.Error()
+		printUpdate(update)
+		return update
+	}
+
+	var err error
+	if update.From, err = git(appPath, "rev-parse", "HEAD"); err != nil {
+		return fail(err)
+	}
+	target, err := git(appPath, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		remote, _, rerr := upstreamRef(appPath, ref)
+		if rerr != nil {
+			return fail(rerr)
+		}
+		if _, err := git(appPath, "fetch", "--tags", remote); err != nil {
+			return fail(err)
+		}
+		// A branch name resolves through its remote-tracking ref
+		if target, err = git(appPath, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+			if target, err = git(appPath, "rev-parse", "--verify", "--quiet", remote+"/"+ref+"^{commit}"); err != nil {
+				return fail(fmt.Errorf("ref %s not found in app %s", ref, app))
+			}
+		}
+	}
+	update.To = target
+	if target == update.From {
+		return update
+	}
+
+	if dirty, err := git(appPath, "status", "--porcelain", "--untracked-files=no"); err != nil {
+		return fail(err)
+	} else if dirty != "" {
+		update.To = ""
+		update.Skipped = "working tree has uncommitted changes"
+		printUpdate(update)
+		return update
+	}
+	if update.Commits, err = changelog(appPath, update.From, target); err != nil {
+		return fail(err)
+	}
+	if _, err := git(appPath, "checkout", "--quiet", "--detach", target); err != nil {
+		return fail(err)
+	}
+	fmt.Printf("[APPS] Pinned app %s to %s (%s)\n", app, ref, short(target))
+	return update
+}
+
+func printUpdate(u AppUpdate) {
+	switch {
+	case u.Error != "":
+		fmt.Printf("[ERROR] Failed to update app %s: %s\n", u.App, u.Error)
+	case u.Skipped != "":
+		fmt.Printf("[WARN] Not updating app %s: %s\n", u.App, u.Skipped)
+	case !u.Changed():
+		fmt.Printf("[APPS] %s (%s) is up to date at %s\n", u.App, u.Branch, short(u.From))
+	default:
+		fmt.Printf("[APPS] %s (%s) updated %s..%s, %d commit(s):\n", u.App, u.Branch, short(u.From), short(u.To), len(u.Commits))
+		for _, c := range u.Commits {
+			fmt.Printf("         %s\n", c)
+		}
+	}
+}
+
+func short(commit string) string {
+	if len(commit) > 8 {
+		return commit[:8]
+	}
+	return commit
 }
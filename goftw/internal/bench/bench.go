@@ -11,12 +11,17 @@ import (
 
 // RunInBenchSwallowIO executes a bench command inside the bench directory and returns its output.
 func RunInBenchSwallowIO(args ...string) (string, error) {
+	return runInBench(nil, args...)
+}
+
+// runInBench is RunInBenchSwallowIO with extra environment variables
+func runInBench(env []string, args ...string) (string, error) {
 	benchDir := environ.GetBenchPath()
 
 	// Directly run bench with Dir set to benchDir
 	cmd := exec.Command("bench", args...)
 	cmd.Dir = benchDir
-	cmd.Env = os.Environ() // inherit environment variables
+	cmd.Env = append(os.Environ(), env...) // inherit environment variables
 
 	var out bytes.Buffer
 	var stderr bytes.Buffer
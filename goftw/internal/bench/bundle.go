@@ -0,0 +1,342 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"goftw/internal/environ"
+)
+
+// Build backends of frappe apps; they are bundled as wheels so apps install without build isolation
+var buildRequirements = []string{"flit_core", "setuptools", "wheel"}
+
+// BundleManifest describes an offline bundle of a bench
+type BundleManifest struct {
+	CreatedAt    time.Time   `json:"created_at"`
+	FrappeBranch string      `json:"frappe_branch"`
+	Python       string      `json:"python"` // python version of the exporting bench
+	Apps         []BundleApp `json:"apps"`   // in sites/apps.txt order
+	Files        []string    `json:"files"`  // built files, relative to the bench
+}
+
+// BundleApp locks one app of a bundle to a commit
+type BundleApp struct {
+	App    string `json:"app"`
+	Branch string `json:"branch"` // "HEAD" when the app was on a detached HEAD
+	Commit string `json:"commit"`
+	Remote string `json:"remote"`
+}
+
+// ExportBundle writes an archive with everything needed to rebuild the bench without network
+// access: a git bundle of every app at its checked out commit, wheels of the Python
+// environment, node_modules and built assets. Local development apps cannot be bundled, so
+// the export fails on them unless skipLocalApps leaves them out of the bundle.
+func ExportBundle(benchDir, frappeBranch, out string, skipLocalApps bool) error {
+	out, err := filepath.Abs(out)
+	if err != nil {
+		return err
+	}
+	all, err := bundleApps(benchDir)
+	if err != nil {
+		return err
+	}
+	var apps []string
+	for _, app := range all {
+		if !IsLocalApp(benchDir, app) {
+			apps = append(apps, app)
+			continue
+		}
+		if !skipLocalApps {
+			return fmt.Errorf("app %s is a local development app and cannot be bundled, pass --skip-local-apps to leave it out", app)
+		}
+		fmt.Printf("[WARN] Not bundling local development app %s\n", app)
+	}
+	staging, err := os.MkdirTemp(filepath.Dir(out), ".goftw-bundle-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+	if err := os.MkdirAll(filepath.Join(staging, "repos"), 0755); err != nil {
+		return err
+	}
+
+	manifest := BundleManifest{CreatedAt: time.Now().UTC(), FrappeBranch: frappeBranch}
+	python := filepath.Join(benchDir, "env", "bin", "python")
+	if v, err := runIn(benchDir, python, "--version"); err == nil {
+		manifest.Python = strings.TrimPrefix(v, "Python ")
+	}
+
+	for _, app := range apps {
+		appPath := filepath.Join(benchDir, "apps", app)
+		branch, err := git(appPath, "rev-parse", "--abbrev-ref", "HEAD")
+		if err != nil {
+			return err
+		}
+		commit, err := AppCommit(benchDir, app)
+		if err != nil {
+			return err
+		}
+		refs := []string{"HEAD"}
+		if branch != "HEAD" {
+			refs = append(refs, branch)
+		}
+		fmt.Printf("[BUNDLE] App %s: %s at %s\n", app, branch, short(commit))
+		bundle := filepath.Join(staging, "repos", app+".bundle")
+		if _, err := git(appPath, append([]string{"bundle", "create", bundle}, refs...)...); err != nil {
+			return err
+		}
+		manifest.Apps = append(manifest.Apps, BundleApp{App: app, Branch: branch, Commit: commit, Remote: AppRemote(app)})
+
+		for _, built := range []string{filepath.Join("apps", app, "node_modules"), filepath.Join("apps", app, app, "public", "dist")} {
+			if _, err := os.Stat(filepath.Join(benchDir, built)); err == nil {
+				manifest.Files = append(manifest.Files, built)
+			}
+		}
+	}
+	if _, err := os.Stat(filepath.Join(benchDir, "sites", "assets")); err == nil {
+		manifest.Files = append(manifest.Files, filepath.Join("sites", "assets"))
+	}
+
+	// Editable installs are the apps themselves, which come from their git bundles. Wheels are
+	// built from pip freeze, whose "pkg @ git+https://..." lines need the network, while the
+	// import installs from "pkg==version" lines that the wheels satisfy offline.
+	fmt.Println("[BUNDLE] Building wheels of the Python environment ...")
+	frozen, err := runIn(benchDir, python, "-m", "pip", "freeze", "--exclude-editable")
+	if err != nil {
+		return err
+	}
+	pinned, err := runIn(benchDir, python, "-m", "pip", "list", "--format=freeze", "--exclude-editable")
+	if err != nil {
+		return err
+	}
+	sources := filepath.Join(staging, "sources.txt")
+	if err := os.WriteFile(sources, []byte(frozen+"\n"), 0644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(staging, "requirements.txt"), []byte(pinned+"\n"), 0644); err != nil {
+		return err
+	}
+	wheelArgs := append([]string{"-m", "pip", "wheel", "--wheel-dir", filepath.Join(staging, "wheels"), "-r", sources}, buildRequirements...)
+	if _, err := runIn(benchDir, python, wheelArgs...); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(staging, "manifest.json"), data, 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("[BUNDLE] Writing %s ...\n", out)
+	tarArgs := append([]string{"-czf", out, "-C", staging, "manifest.json", "requirements.txt", "sources.txt", "repos", "wheels", "-C", benchDir}, manifest.Files...)
+	if _, err := runIn(benchDir, "tar", tarArgs...); err != nil {
+		return err
+	}
+	fmt.Printf("[BUNDLE] Exported %d app(s) to %s\n", len(manifest.Apps), out)
+	return nil
+}
+
+// importMarker exists in a bench while ImportBundle builds it; a bench holding it is incomplete
+const importMarker = ".goftw-import-incomplete"
+
+// ImportIncomplete reports whether benchDir was left behind by an import that did not finish
+func ImportIncomplete(benchDir string) bool {
+	_, err := os.Stat(filepath.Join(benchDir, importMarker))
+	return err == nil
+}
+
+// ImportBundle builds a new bench at benchDir from an archive written by ExportBundle,
+// without network access. Apps are checked out at their locked commits with their real
+// remotes, so later updates fetch from the network as usual.
+//
+// Apps and built files are assembled in a temporary directory next to benchDir, which is
+// renamed into place once complete. The virtualenv records its own path, so it is created
+// after the rename; if that fails, the bench is removed again. The import marker covers a
+// crash in between.
+func ImportBundle(bundlePath, benchDir string) (err error) {
+	if _, err := os.Stat(benchDir); err == nil {
+		return fmt.Errorf("bench directory %s already exists", benchDir)
+	}
+	bundlePath, err = filepath.Abs(bundlePath)
+	if err != nil {
+		return err
+	}
+	staging, err := os.MkdirTemp(filepath.Dir(benchDir), ".goftw-bundle-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	fmt.Printf("[BUNDLE] Unpacking %s ...\n", bundlePath)
+	if _, err := runIn(staging, "tar", "-xzf", bundlePath); err != nil {
+		return err
+	}
+	var manifest BundleManifest
+	data, err := os.ReadFile(filepath.Join(staging, "manifest.json"))
+	if err != nil {
+		return fmt.Errorf("not a goftw bundle: %v", err)
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("invalid bundle manifest: %v", err)
+	}
+	fmt.Printf("[BUNDLE] Bundle of %d app(s) exported %s (frappe %s, python %s)\n",
+		len(manifest.Apps), manifest.CreatedAt.Format(time.RFC3339), manifest.FrappeBranch, manifest.Python)
+
+	// Wheels only install on the Python version they were built for
+	target, err := runIn(staging, "python3", "--version")
+	if err != nil {
+		return err
+	}
+	if !samePythonVersion(manifest.Python, strings.TrimPrefix(target, "Python ")) {
+		return fmt.Errorf("bundle was built for python %s, this machine has %s", manifest.Python, target)
+	}
+
+	build, err := os.MkdirTemp(filepath.Dir(benchDir), "."+filepath.Base(benchDir)+"-import-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(build)
+	if err := os.WriteFile(filepath.Join(build, importMarker), nil, 0644); err != nil {
+		return err
+	}
+	for _, dir := range []string{"apps", "sites", "logs", filepath.Join("config", "pids")} {
+		if err := os.MkdirAll(filepath.Join(build, dir), 0755); err != nil {
+			return err
+		}
+	}
+
+	var appNames []string
+	for _, app := range manifest.Apps {
+		fmt.Printf("[BUNDLE] App %s: %s at %s\n", app.App, app.Branch, short(app.Commit))
+		appPath := filepath.Join(build, "apps", app.App)
+		repo := filepath.Join(staging, "repos", app.App+".bundle")
+		if _, err := runIn(build, "git", "clone", "--quiet", "--origin", "upstream", repo, appPath); err != nil {
+			return err
+		}
+		checkout := []string{"-C", appPath, "checkout", "--quiet", app.Commit}
+		if app.Branch != "HEAD" {
+			checkout = []string{"-C", appPath, "checkout", "--quiet", "-B", app.Branch, app.Commit}
+		}
+		if _, err := runIn(build, "git", checkout...); err != nil {
+			return err
+		}
+		if _, err := runIn(build, "git", "-C", appPath, "remote", "set-url", "upstream", app.Remote); err != nil {
+			return err
+		}
+		appNames = append(appNames, app.App)
+	}
+
+	// Built files were unpacked next to the bundle's metadata, with their bench-relative paths
+	for _, file := range manifest.Files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(build, file)), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(filepath.Join(staging, file), filepath.Join(build, file)); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(filepath.Join(build, "sites", "apps.txt"), []byte(strings.Join(appNames, "\n")+"\n"), 0644); err != nil {
+		return err
+	}
+
+	if err := os.Rename(build, benchDir); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			fmt.Printf("[ERROR] Import failed, removing incomplete bench %s\n", benchDir)
+			_ = os.RemoveAll(benchDir)
+		}
+	}()
+
+	if err := CopyCommonSitesConfig(benchDir, environ.GetCommonSitesConfigPath()); err != nil {
+		return err
+	}
+
+	fmt.Println("[BUNDLE] Installing the Python environment from wheels ...")
+	wheels := filepath.Join(staging, "wheels")
+	python := filepath.Join(benchDir, "env", "bin", "python")
+	offline := []string{"-m", "pip", "install", "--quiet", "--no-index", "--find-links", wheels}
+	steps := [][]string{
+		{"python3", "-m", "venv", filepath.Join(benchDir, "env")},
+		append(append([]string{python}, offline...), buildRequirements...),
+		append([]string{python}, append(offline, "-r", filepath.Join(staging, "requirements.txt"))...),
+	}
+	for _, app := range appNames {
+		steps = append(steps, append([]string{python}, append(offline, "--no-deps", "--no-build-isolation", "-e", filepath.Join(benchDir, "apps", app))...))
+	}
+	for _, step := range steps {
+		if _, err := runIn(benchDir, step[0], step[1:]...); err != nil {
+			return err
+		}
+	}
+
+	// What bench init does after fetching frappe; neither needs the network
+	for _, args := range [][]string{{"setup", "redis"}, {"setup", "procfile"}} {
+		if _, err := runInBench(nil, args...); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(filepath.Join(benchDir, importMarker)); err != nil {
+		return err
+	}
+	fmt.Printf("[BUNDLE] Bench %s imported\n", benchDir)
+	return nil
+}
+
+// samePythonVersion compares the major and minor parts of two python versions
+func samePythonVersion(a, b string) bool {
+	majorMinor := func(v string) string {
+		parts := strings.SplitN(strings.TrimSpace(v), ".", 3)
+		if len(parts) < 2 {
+			return v
+		}
+		return parts[0] + "." + parts[1]
+	}
+	return a != "" && majorMinor(a) == majorMinor(b)
+}
+
+// bundleApps lists the bench's apps in sites/apps.txt order, followed by any app missing from it
+func bundleApps(benchDir string) ([]string, error) {
+	apps, err := ListApps(benchDir)
+	if err != nil {
+		return nil, err
+	}
+	present := map[string]bool{}
+	for _, app := range apps {
+		present[app] = true
+	}
+	var ordered []string
+	if data, err := os.ReadFile(filepath.Join(benchDir, "sites", "apps.txt")); err == nil {
+		for _, app := range strings.Fields(string(data)) {
+			if present[app] {
+				ordered = append(ordered, app)
+				delete(present, app)
+			}
+		}
+	}
+	for _, app := range apps {
+		if present[app] {
+			ordered = append(ordered, app)
+		}
+	}
+	return ordered, nil
+}
+
+// runIn runs a command in dir and returns its trimmed combined output
+func runIn(dir, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s %s failed: %v: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
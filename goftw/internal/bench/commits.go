@@ -0,0 +1,46 @@
+package bench
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"goftw/internal/sudo"
+)
+
+// AppCommits returns the commit currently checked out for every app in the bench.
+func AppCommits(benchDir string) (map[string]string, error) {
+	apps, err := ListApps(benchDir)
+	if err != nil {
+		return nil, err
+	}
+	commits := map[string]string{}
+	for _, app := range apps {
+		// Rolling back must never reset a developer's work in progress
+		if IsLocalApp(benchDir, app) {
+			continue
+		}
+		commit, err := AppCommit(benchDir, app)
+		if err != nil {
+			fmt.Printf("[WARN] Could not read commit of app %s: %v\n", app, err)
+			continue
+		}
+		commits[app] = commit
+	}
+	return commits, nil
+}
+
+// AppCommit returns the commit checked out for an app
+func AppCommit(benchDir, app string) (string, error) {
+	out, err := sudo.RunInBenchSwallowIO("git", "-C", filepath.Join(benchDir, "apps", app), "rev-parse", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse failed: %v: %s", err, out)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// ResetApp moves an app back to the given commit, discarding the checked out one.
+func ResetApp(benchDir, app, commit string) error {
+	fmt.Printf("[APPS] Resetting app %s to %s\n", app, commit)
+	return sudo.RunInBenchPrintIO("git", "-C", filepath.Join(benchDir, "apps", app), "reset", "--hard", commit)
+}
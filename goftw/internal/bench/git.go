@@ -0,0 +1,51 @@
+package bench
+
+import (
+	"fmt"
+	"strings"
+
+	"goftw/internal/sudo"
+)
+
+// git runs a git command in an app checkout and returns its trimmed output
+func git(appPath string, args ...string) (string, error) {
+	out, err := sudo.RunInBenchSwallowIO(append([]string{"git", "-C", appPath}, args...)...)
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// upstreamRef returns the remote and the remote-tracking ref an app's branch follows.
+// Apps fetched by bench track nothing, so the "upstream" remote (or the first one) is used.
+func upstreamRef(appPath, branch string) (string, string, error) {
+	remote, _ := git(appPath, "config", "--get", "branch."+branch+".remote")
+	merge, _ := git(appPath, "config", "--get", "branch."+branch+".merge")
+	if remote != "" && remote != "." && strings.HasPrefix(merge, "refs/heads/") {
+		return remote, remote + "/" + strings.TrimPrefix(merge, "refs/heads/"), nil
+	}
+	out, err := git(appPath, "remote")
+	if err != nil {
+		return "", "", err
+	}
+	remotes := strings.Fields(out)
+	if len(remotes) == 0 {
+		return "", "", fmt.Errorf("no git remote configured")
+	}
+	remote = remotes[0]
+	for _, r := range remotes {
+		if r == "upstream" {
+			remote = r
+		}
+	}
+	return remote, remote + "/" + branch, nil
+}
+
+// changelog lists "<short hash> <subject>" for the commits in from..to, newest first
+func changelog(appPath, from, to string) ([]string, error) {
+	out, err := git(appPath, "log", "--format=%h %s", from+".."+to)
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
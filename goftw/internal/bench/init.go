@@ -7,6 +7,7 @@ import (
 	"goftw/internal/whoami"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // Initialize initializes a new bench with the given name and frappe branch
@@ -30,8 +31,22 @@ func Initialize(benchName, frappeBranch string) error {
 		return fmt.Errorf("failed to chown parent directory: %w", err)
 	}
 
-	// Run bench init
+	// An offline bundle replaces bench init entirely
+	if bundle := environ.GetBundleFile(); bundle != "" {
+		fmt.Printf("[INFO] Importing bench '%s' from bundle %s\n", benchName, bundle)
+		return ImportBundle(bundle, benchPath)
+	}
+
+	// Run bench init, cloning frappe from its mirror when mirrors are enabled
 	cmd := fmt.Sprintf("bench init --frappe-branch %s %s", frappeBranch, benchPath)
+	env, err := mirrorEnv("frappe")
+	if err != nil {
+		return err
+	}
+	if env != nil {
+		cmd = fmt.Sprintf("env '%s' bench init --frappe-branch %s --frappe-path '%s' %s",
+			strings.Join(env, "' '"), frappeBranch, AppRemote("frappe"), benchPath)
+	}
 	if err := whoami.RunPrintIO("sh", "-c", cmd); err != nil {
 		return fmt.Errorf("[ERROR] Bench initialization failed: %w", err)
 	}
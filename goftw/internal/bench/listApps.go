@@ -23,6 +23,12 @@ func ListApps(benchDir string) ([]string, error) {
 			continue
 		}
 
+		// Local development apps need not be git repositories
+		if IsLocalApp(benchDir, filepath.Base(d)) {
+			apps = append(apps, filepath.Base(d))
+			continue
+		}
+
 		// Check if directory is a git repository
 		gitDir := filepath.Join(d, ".git")
 		if _, err := os.Stat(gitDir); os.IsNotExist(err) {
@@ -0,0 +1,65 @@
+package bench
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"goftw/internal/config"
+	"goftw/internal/environ"
+)
+
+// IsLocalApp reports whether an app of the bench is a local development app, i.e. a link
+// to a directory outside the bench.
+func IsLocalApp(benchDir, app string) bool {
+	info, err := os.Lstat(filepath.Join(benchDir, "apps", app))
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
+
+// linkLocalApp links a local development app into the bench, installs it in editable mode
+// and registers it in sites/apps.txt. Edits to the app are live without fetching or updating.
+func linkLocalApp(app, ref string) error {
+	benchDir := environ.GetBenchPath()
+	path := config.LocalAppPath(ref)
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return fmt.Errorf("local app %s: %s is not a directory", app, path)
+	}
+
+	fmt.Printf("[APPS] Linking local app %s from %s\n", app, path)
+	appPath := filepath.Join(benchDir, "apps", app)
+	if err := os.Symlink(path, appPath); err != nil {
+		return err
+	}
+	python := filepath.Join(benchDir, "env", "bin", "python")
+	if _, err := runIn(benchDir, python, "-m", "pip", "install", "--quiet", "-e", appPath); err != nil {
+		_ = os.Remove(appPath)
+		return err
+	}
+	if err := registerApp(benchDir, app); err != nil {
+		return err
+	}
+	if err := RunInBenchPrintIO("build", "--app", app); err != nil {
+		fmt.Printf("[WARN] Failed to build assets of local app %s: %v\n", app, err)
+	}
+	return nil
+}
+
+// registerApp adds an app to sites/apps.txt unless it is listed already
+func registerApp(benchDir, app string) error {
+	path := filepath.Join(benchDir, "sites", "apps.txt")
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, listed := range strings.Fields(string(data)) {
+		if listed == app {
+			return nil
+		}
+	}
+	content := strings.TrimRight(string(data), "\n")
+	if content != "" {
+		content += "\n"
+	}
+	return os.WriteFile(path, []byte(content+app+"\n"), 0644)
+}
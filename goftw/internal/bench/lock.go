@@ -0,0 +1,14 @@
+package bench
+
+import "goftw/internal/state"
+
+// WithSharedLock serializes operations that change state shared by every site of the bench
+// (apps/, sites/apps.txt, built assets, common_site_config.json) across goftw processes.
+func WithSharedLock(fn func() error) error {
+	unlock, err := state.Lock("bench")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return fn()
+}
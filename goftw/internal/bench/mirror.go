@@ -0,0 +1,195 @@
+package bench
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"goftw/internal/environ"
+	"goftw/internal/state"
+)
+
+// AppRemote returns the git URL an app is cloned from: the one declared in mirrors.remotes,
+// else the remote of the app's checkout in the bench, else the frappe GitHub organisation.
+func AppRemote(app string) string {
+	if mirrors := mirrorSettings(); mirrors != nil {
+		if url, ok := mirrors.Remotes[app]; ok && url != "" {
+			return url
+		}
+	}
+	appPath := environ.GetBenchAppPath(app)
+	if _, err := os.Stat(appPath); err == nil {
+		if branch, err := git(appPath, "rev-parse", "--abbrev-ref", "HEAD"); err == nil {
+			if remote, _, err := upstreamRef(appPath, branch); err == nil {
+				if url, err := git(appPath, "remote", "get-url", remote); err == nil && url != "" {
+					return url
+				}
+			}
+		}
+	}
+	return "https://github.com/frappe/" + app
+}
+
+// MirrorPath returns where the bare mirror of an app lives
+func MirrorPath(app string) string {
+	return filepath.Join(environ.GetMirrorPath(), app+".git")
+}
+
+// EnsureMirror creates the mirror of an app unless it already exists (e.g. pre-seeded)
+func EnsureMirror(app string) error {
+	unlock, err := state.Lock("mirror-" + app)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	path := MirrorPath(app)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if mirrors := mirrorSettings(); mirrors != nil && mirrors.Offline {
+		return fmt.Errorf("no mirror of %s in %s and mirrors are offline", app, environ.GetMirrorPath())
+	}
+	if err := os.MkdirAll(environ.GetMirrorPath(), 0755); err != nil {
+		return err
+	}
+	fmt.Printf("[MIRROR] Creating mirror of %s from %s\n", app, AppRemote(app))
+	// Clone next to the final path so an interrupted clone never looks like a mirror
+	tmp := path + ".tmp"
+	_ = os.RemoveAll(tmp)
+	if out, err := exec.Command("git", "clone", "--mirror", AppRemote(app), tmp).CombinedOutput(); err != nil {
+		_ = os.RemoveAll(tmp)
+		return fmt.Errorf("git clone --mirror %s failed: %v: %s", AppRemote(app), err, strings.TrimSpace(string(out)))
+	}
+	return os.Rename(tmp, path)
+}
+
+// RefreshMirror fetches new commits into an app's mirror
+func RefreshMirror(app string) error {
+	unlock, err := state.Lock("mirror-" + app)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	out, err := exec.Command("git", "--git-dir", MirrorPath(app), "remote", "update", "--prune").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("refreshing mirror of %s failed: %v: %s", app, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// MirrorRemote returns the URL an app's mirror fetches from
+func MirrorRemote(app string) (string, error) {
+	out, err := exec.Command("git", "--git-dir", MirrorPath(app), "remote", "get-url", "origin").Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// MirroredApps lists the apps that have a mirror
+func MirroredApps() ([]string, error) {
+	entries, err := os.ReadDir(environ.GetMirrorPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var apps []string
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".git"); ok && e.IsDir() {
+			apps = append(apps, name)
+		}
+	}
+	sort.Strings(apps)
+	return apps, nil
+}
+
+// mirrorEnv returns the environment that makes git clone an app's remote from its mirror.
+// The rewrite only lives in the environment of that command, so the checkout keeps the
+// real remote URL and later fetches go to the network as usual. Without mirrors, or when
+// a mirror cannot be created, it returns nil and the clone uses the network.
+func mirrorEnv(app string) ([]string, error) {
+	mirrors := mirrorSettings()
+	if mirrors == nil {
+		return nil, nil
+	}
+	if err := EnsureMirror(app); err != nil {
+		if mirrors.Offline {
+			return nil, err
+		}
+		fmt.Printf("[WARN] Cloning %s from the network: %v\n", app, err)
+		return nil, nil
+	}
+	return []string{
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=url.file://" + MirrorPath(app) + ".insteadOf",
+		"GIT_CONFIG_VALUE_0=" + AppRemote(app),
+	}, nil
+}
+
+// MirrorLoop mirrors every app of the bench and refreshes all mirrors every refresh_interval.
+// It never returns and is meant to run in a goroutine. It idles while mirrors are disabled or
+// offline, and picks up mirrors enabled by a hot reload within a minute.
+func MirrorLoop(benchDir string) {
+	for {
+		mirrors := mirrorSettings()
+		if mirrors == nil || mirrors.Offline {
+			time.Sleep(time.Minute)
+			continue
+		}
+		interval := 6 * time.Hour
+		if d, err := time.ParseDuration(mirrors.RefreshInterval); err == nil && d > 0 {
+			interval = d
+		}
+		fmt.Printf("[MIRROR] Refreshing mirrors in %s, next refresh in %s\n", environ.GetMirrorPath(), interval)
+		SyncMirrors(benchDir)
+		time.Sleep(interval)
+	}
+}
+
+// SyncMirrors creates missing mirrors for the bench's apps and refreshes the existing ones,
+// logging failures instead of stopping. It returns the number of apps that failed.
+func SyncMirrors(benchDir string, apps ...string) int {
+	if len(apps) == 0 {
+		apps, _ = ListApps(benchDir)
+		mirrored, err := MirroredApps()
+		if err != nil {
+			fmt.Printf("[ERROR] Failed to list mirrors: %v\n", err)
+		}
+		apps = union(apps, mirrored)
+	}
+
+	failed := 0
+	for _, app := range apps {
+		if IsLocalApp(benchDir, app) {
+			continue
+		}
+		_, statErr := os.Stat(MirrorPath(app))
+		err := EnsureMirror(app)
+		if err == nil && statErr == nil {
+			err = RefreshMirror(app)
+		}
+		if err != nil {
+			fmt.Printf("[ERROR] Mirror of %s: %v\n", app, err)
+			failed++
+		}
+	}
+	return failed
+}
+
+func union(a, b []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, x := range append(append([]string{}, a...), b...) {
+		if !seen[x] {
+			seen[x] = true
+			out = append(out, x)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
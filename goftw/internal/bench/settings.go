@@ -0,0 +1,60 @@
+package bench
+
+import (
+	"sync"
+
+	"goftw/internal/config"
+)
+
+// Parts of instance.json that bench operations depend on. Hot reload replaces them while
+// other goroutines fetch and update apps, so they are only accessed under settingsMu.
+var (
+	settingsMu sync.RWMutex
+	mirrors    *config.MirrorsConfig
+	localApps  = map[string]string{}
+	pinnedRefs = map[string]string{}
+)
+
+// SetMirrors enables cloning from local git mirrors, as declared in instance.json. nil disables it.
+func SetMirrors(cfg *config.MirrorsConfig) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	mirrors = cfg
+}
+
+// SetLocalApps declares the local development apps (app -> path) from instance.json
+func SetLocalApps(apps map[string]string) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	localApps = apps
+}
+
+// SetPinnedRefs declares the refs pinned apps are checked out at (app -> commit, tag or branch)
+func SetPinnedRefs(refs map[string]string) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	pinnedRefs = refs
+}
+
+// mirrorSettings returns the mirrors config in effect, nil when mirrors are disabled
+func mirrorSettings() *config.MirrorsConfig {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return mirrors
+}
+
+// LocalAppRef returns the path instance.json declares for a local development app
+func LocalAppRef(app string) (string, bool) {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	ref, ok := localApps[app]
+	return ref, ok
+}
+
+// pinnedRef returns the ref a pinned app is checked out at
+func pinnedRef(app string) (string, bool) {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	ref, ok := pinnedRefs[app]
+	return ref, ok
+}
@@ -0,0 +1,67 @@
+package certs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"goftw/internal/config"
+	"goftw/internal/sudo"
+)
+
+// issueACME obtains a certificate from the configured ACME directory using certbot.
+// The standalone challenge binds the HTTP port itself; otherwise challenges are
+// written to ACMEWebroot, which nginx serves on every server block.
+func issueACME(cfg *config.TLSConfig, siteName string, names []string, standalone bool) error {
+	if cfg.ACMEDirectory == "" {
+		return fmt.Errorf("tls.acme_directory is required in acme mode")
+	}
+	base := certbotDir(cfg)
+	args := []string{
+		"certonly", "--non-interactive", "--agree-tos",
+		"--server", cfg.ACMEDirectory,
+		"--cert-name", siteName,
+		"--config-dir", filepath.Join(base, "config"),
+		"--work-dir", filepath.Join(base, "work"),
+		"--logs-dir", filepath.Join(base, "logs"),
+		"--expand", "--force-renewal",
+	}
+	if cfg.ACMEEmail != "" {
+		args = append(args, "--email", cfg.ACMEEmail)
+	} else {
+		args = append(args, "--register-unsafely-without-email")
+	}
+	if standalone {
+		port := cfg.HTTPPort
+		if port == 0 {
+			port = 80
+		}
+		args = append(args, "--standalone", "--http-01-port", strconv.Itoa(port))
+	} else {
+		if err := os.MkdirAll(ACMEWebroot(), 0755); err != nil {
+			return err
+		}
+		args = append(args, "--webroot", "-w", ACMEWebroot())
+	}
+	for _, name := range names {
+		args = append(args, "-d", name)
+	}
+
+	// certbot needs root to bind the challenge port; pass the CA bundle through sudo's clean env
+	envArgs := []string{"env"}
+	if cfg.ACMECABundle != "" {
+		// Trust a private ACME server such as Pebble
+		envArgs = append(envArgs, "REQUESTS_CA_BUNDLE="+cfg.ACMECABundle)
+	}
+	if err := sudo.RunPrintIO(append(append(envArgs, "certbot"), args...)...); err != nil {
+		return fmt.Errorf("certbot failed: %v", err)
+	}
+
+	// Hand the certbot tree back to the bench user so goftw can inspect expiry without sudo
+	owner := fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid())
+	if err := sudo.RunPrintIO("chown", "-R", owner, base); err != nil {
+		return fmt.Errorf("failed to chown %s: %v", base, err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package certs
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"goftw/internal/config"
+	"goftw/internal/environ"
+)
+
+const (
+	ModeSelfSigned = "self_signed"
+	ModeLocalCA    = "local_ca"
+	ModeACME       = "acme"
+)
+
+// Paths returns the certificate chain and private key paths for a site.
+func Paths(cfg *config.TLSConfig, siteName string) (string, string) {
+	if cfg.Mode == ModeACME {
+		live := filepath.Join(certbotDir(cfg), "config", "live", siteName)
+		return filepath.Join(live, "fullchain.pem"), filepath.Join(live, "privkey.pem")
+	}
+	dir := filepath.Join(certDir(cfg), siteName)
+	return filepath.Join(dir, "fullchain.pem"), filepath.Join(dir, "privkey.pem")
+}
+
+// Ensure makes sure a valid certificate covering all names exists for the site,
+// issuing or renewing it when missing, expiring soon or not covering every name.
+// standalone selects the ACME standalone challenge, used while nginx is not running yet.
+// It reports whether a new certificate was written.
+func Ensure(cfg *config.TLSConfig, siteName string, names []string, standalone bool) (bool, error) {
+	certPath, _ := Paths(cfg, siteName)
+	reason := needsIssue(cfg, certPath, names)
+	if reason == "" {
+		return false, nil
+	}
+	fmt.Printf("[TLS] Issuing certificate for site %s (%s) via %s\n", siteName, reason, cfg.Mode)
+
+	var err error
+	switch cfg.Mode {
+	case ModeSelfSigned, ModeLocalCA:
+		err = issueLocal(cfg, siteName, names)
+	case ModeACME:
+		err = issueACME(cfg, siteName, names, standalone)
+	default:
+		err = fmt.Errorf("unknown tls mode %q", cfg.Mode)
+	}
+	if err != nil {
+		fmt.Printf("[ERROR] Failed to issue certificate for site %s: %v\n", siteName, err)
+		return false, err
+	}
+	return true, nil
+}
+
+// needsIssue returns why a certificate must be (re)issued, or "" when the current one is fine.
+func needsIssue(cfg *config.TLSConfig, certPath string, names []string) string {
+	leaf, err := readLeaf(certPath)
+	if err != nil {
+		return "missing"
+	}
+	if time.Until(leaf.NotAfter) < renewBefore(cfg) {
+		return fmt.Sprintf("expires %s", leaf.NotAfter.Format(time.DateOnly))
+	}
+	for _, name := range names {
+		if err := leaf.VerifyHostname(name); err != nil {
+			return fmt.Sprintf("does not cover %s", name)
+		}
+	}
+	return ""
+}
+
+// readLeaf parses the first certificate of a PEM chain.
+func readLeaf(path string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no certificate found in %s", path)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func renewBefore(cfg *config.TLSConfig) time.Duration {
+	days := cfg.RenewBeforeDays
+	if days <= 0 {
+		days = 30
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func certDir(cfg *config.TLSConfig) string {
+	if cfg.CertDir != "" {
+		return cfg.CertDir
+	}
+	return filepath.Join(environ.GetStatePath(), "certs")
+}
+
+func certbotDir(cfg *config.TLSConfig) string {
+	return filepath.Join(certDir(cfg), "certbot")
+}
+
+// ACMEWebroot is the directory nginx serves /.well-known/acme-challenge/ from.
+func ACMEWebroot() string {
+	return filepath.Join(environ.GetStatePath(), "acme-webroot")
+}
@@ -0,0 +1,151 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"goftw/internal/config"
+)
+
+const (
+	leafValidity = 397 * 24 * time.Hour
+	caValidity   = 10 * 365 * 24 * time.Hour
+)
+
+// issueLocal writes a self-signed or local-CA-signed certificate for the site.
+func issueLocal(cfg *config.TLSConfig, siteName string, names []string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	template, err := newTemplate(names[0], leafValidity)
+	if err != nil {
+		return err
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	for _, name := range names {
+		if ip := net.ParseIP(name); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, name)
+		}
+	}
+
+	parent, signer := template, any(key)
+	var chain []byte
+	if cfg.Mode == ModeLocalCA {
+		caCert, caKey, caPEM, err := loadOrCreateCA(cfg)
+		if err != nil {
+			return fmt.Errorf("local CA: %v", err)
+		}
+		parent, signer, chain = caCert, caKey, caPEM
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	if err != nil {
+		return err
+	}
+	certPath, keyPath := Paths(cfg, siteName)
+	leafPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return writeKeyPair(certPath, keyPath, append(leafPEM, chain...), key)
+}
+
+// loadOrCreateCA returns the local CA used to sign site certificates, creating it on first use.
+func loadOrCreateCA(cfg *config.TLSConfig) (*x509.Certificate, *ecdsa.PrivateKey, []byte, error) {
+	certPath := filepath.Join(certDir(cfg), "ca", "ca.crt")
+	keyPath := filepath.Join(certDir(cfg), "ca", "ca.key")
+
+	if certPEM, err := os.ReadFile(certPath); err == nil {
+		keyPEM, err := os.ReadFile(keyPath)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		certBlock, _ := pem.Decode(certPEM)
+		keyBlock, _ := pem.Decode(keyPEM)
+		if certBlock == nil || keyBlock == nil {
+			return nil, nil, nil, fmt.Errorf("invalid CA files in %s", filepath.Dir(certPath))
+		}
+		caCert, err := x509.ParseCertificate(certBlock.Bytes)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		caKey, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return caCert, caKey, certPEM, nil
+	}
+
+	fmt.Printf("[TLS] Creating local CA at %s\n", certPath)
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	template, err := newTemplate("goftw local CA", caValidity)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	caCert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := writeKeyPair(certPath, keyPath, certPEM, caKey); err != nil {
+		return nil, nil, nil, err
+	}
+	return caCert, caKey, certPEM, nil
+}
+
+func newTemplate(commonName string, validity time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"goftw"}},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(validity),
+	}, nil
+}
+
+// writeKeyPair writes a PEM certificate chain and its private key, the key readable only by the owner.
+func writeKeyPair(certPath, keyPath string, chain []byte, key *ecdsa.PrivateKey) error {
+	if err := os.MkdirAll(filepath.Dir(certPath), 0755); err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(keyPath+".tmp", keyPEM, 0600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(certPath+".tmp", chain, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(keyPath+".tmp", keyPath); err != nil {
+		return err
+	}
+	return os.Rename(certPath+".tmp", certPath)
+}
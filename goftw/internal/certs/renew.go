@@ -0,0 +1,61 @@
+package certs
+
+import (
+	"fmt"
+	"time"
+
+	"goftw/internal/config"
+	"goftw/internal/sudo"
+)
+
+// SiteNames returns every hostname a site's certificate must cover.
+func SiteNames(site config.InstanceSite) []string {
+	return append([]string{site.SiteName}, site.Domains...)
+}
+
+// RenewLoop periodically renews expiring certificates for all sites and reloads nginx
+// whenever a certificate changed. It never returns and is meant to run in a goroutine.
+func RenewLoop(live *config.Live) {
+	for {
+		interval := 12 * time.Hour
+		if cfg := live.Instance().TLS; cfg != nil {
+			if d, err := time.ParseDuration(cfg.RenewInterval); err == nil && d > 0 {
+				interval = d
+			}
+		}
+		time.Sleep(interval)
+
+		// TLS may have been added or removed by a hot reload meanwhile
+		instanceCfg := live.Instance()
+		if instanceCfg.TLS == nil {
+			continue
+		}
+		fmt.Println("[TLS] Checking certificates for renewal")
+		renewed := false
+		for _, site := range instanceCfg.InstanceSites {
+			changed, err := Ensure(instanceCfg.TLS, site.SiteName, SiteNames(site), false)
+			if err != nil {
+				fmt.Printf("[ERROR] Failed to renew certificate for site %s: %v\n", site.SiteName, err)
+				continue
+			}
+			renewed = renewed || changed
+		}
+		if renewed {
+			if err := ReloadNginx(); err != nil {
+				fmt.Printf("[ERROR] Failed to reload nginx after renewal: %v\n", err)
+			}
+		}
+	}
+}
+
+// ReloadNginx validates the nginx configuration and signals nginx to reload it.
+func ReloadNginx() error {
+	if err := sudo.RunPrintIO("nginx", "-t"); err != nil {
+		return fmt.Errorf("nginx config test failed: %v", err)
+	}
+	if err := sudo.RunPrintIO("nginx", "-s", "reload"); err != nil {
+		return fmt.Errorf("nginx reload failed: %v", err)
+	}
+	fmt.Println("[TLS] Nginx reloaded")
+	return nil
+}
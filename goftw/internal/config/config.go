@@ -2,26 +2,197 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+
+	"goftw/internal/environ"
 )
 
 type InstanceConfig struct {
 	Deployment   string `json:"deployment"`
 	FrappeBranch string `json:"frappe_branch"`
 	// BenchName          string         `json:"frappe_bench"`
-	DropAbandonedSites bool           `json:"drop_abandoned_sites"`
-	InstanceSites      []InstanceSite `json:"instance_sites"`
+	DropAbandonedSites bool                 `json:"drop_abandoned_sites"`
+	InstanceSites      []InstanceSite       `json:"instance_sites"`
+	TLS                *TLSConfig           `json:"tls"`
+	ObjectStorage      *ObjectStorageConfig `json:"object_storage"`
+	SafetyBackups      SafetyBackupConfig   `json:"safety_backups"`
+	MaxParallelSites   int                  `json:"max_parallel_sites"` // defaults to 1 (sequential)
+	OnError            string               `json:"on_error"`           // "fail_fast" (default) or "continue"
+	Watch              *WatchConfig         `json:"watch"`
+	DisableHotReload   bool                 `json:"disable_hot_reload"` // apply instance.json changes only on restart
+	AbandonedSites     AbandonedSitesConfig `json:"abandoned_sites"`
+	// DisableUpdateMaintenance keeps sites serving while apps are updated and migrated
+	DisableUpdateMaintenance bool `json:"disable_update_maintenance"`
+	// Workers sizes the production processes; 0 keeps what bench generates
+	Workers WorkersConfig  `json:"workers"`
+	Updates UpdatesConfig  `json:"updates"`
+	Mirrors *MirrorsConfig `json:"mirrors"`
+
+	// LocalApps maps local development apps to the path declared for them in a site's apps
+	LocalApps map[string]string `json:"-"`
+}
+
+// MirrorsConfig makes goftw clone frappe and apps from local bare git mirrors
+type MirrorsConfig struct {
+	RefreshInterval string            `json:"refresh_interval"` // Go duration, defaults to 6h
+	Offline         bool              `json:"offline"`          // only use pre-seeded mirrors, never fetch
+	Remotes         map[string]string `json:"remotes"`          // app -> git URL, defaults to https://github.com/frappe/<app>
+}
+
+// UpdatesConfig controls how apps are updated from their git remotes
+type UpdatesConfig struct {
+	// AllowNonFastForward resets apps whose branch has local commits to the remote branch
+	AllowNonFastForward bool                       `json:"allow_non_fast_forward"`
+	DefaultPolicy       string                     `json:"default_policy"` // "track" (default), "pinned" or "manual"
+	Apps                map[string]AppUpdateConfig `json:"apps"`
+	// Windows restrict automatic updates to these periods; none allows them on every start
+	Windows []UpdateWindow `json:"windows"`
+}
+
+// AppUpdateConfig overrides the update policy of one app
+type AppUpdateConfig struct {
+	Policy string `json:"policy"` // "track", "pinned" or "manual"
+	Ref    string `json:"ref"`    // pinned policy: commit, tag or branch the app is checked out at
+}
+
+// UpdateWindow is a period starting on a cron schedule, e.g. "0 2 * * 6" for "2h"
+type UpdateWindow struct {
+	Schedule string `json:"schedule"`
+	Duration string `json:"duration"`
+}
+
+// WorkersConfig sets process counts in the supervisor config
+type WorkersConfig struct {
+	Gunicorn int `json:"gunicorn"` // defaults to max_workers from common_site_config.json
+	Short    int `json:"short"`
+	Default  int `json:"default"` // when set, the default queue gets its own workers
+	Long     int `json:"long"`
+}
+
+// AbandonedSitesConfig controls what happens to sites on disk that instance.json no longer lists
+type AbandonedSitesConfig struct {
+	Policy          string   `json:"policy"`            // "drop" (default) or "archive"
+	GracePeriodDays int      `json:"grace_period_days"` // archive policy: 0 waits for `sites drop <site>`
+	ProtectedSites  []string `json:"protected_sites"`   // never dropped under any policy
+}
+
+// WatchConfig enables a background watcher that compares the bench with instance.json
+type WatchConfig struct {
+	Interval      string      `json:"interval"`       // Go duration, defaults to 5m
+	AutoReconcile bool        `json:"auto_reconcile"` // checkout sites when drift is found instead of only reporting it
+	WebhookURL    string      `json:"webhook_url"`    // receives a JSON POST for every drift change
+	SMTP          *SMTPConfig `json:"smtp"`
+}
+
+// SMTPConfig sends watcher notifications by mail
+type SMTPConfig struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"` // defaults to 25
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	Username string   `json:"username"`
+	Password string   `json:"password"` // secret reference: env:NAME, file:/path or secret:key
+}
+
+// SafetyBackupConfig controls the backups taken before destructive steps
+type SafetyBackupConfig struct {
+	Disabled      bool `json:"disabled"`
+	RetentionDays int  `json:"retention_days"` // defaults to 7
+}
+
+// ObjectStorageConfig points at an S3-compatible bucket that backups are uploaded to
+type ObjectStorageConfig struct {
+	Endpoint  string `json:"endpoint"` // e.g. http://minio:9000
+	Region    string `json:"region"`   // defaults to us-east-1
+	Bucket    string `json:"bucket"`
+	Prefix    string `json:"prefix"`     // key prefix shared by all sites
+	AccessKey string `json:"access_key"` // secret reference, defaults to env:AWS_ACCESS_KEY_ID
+	SecretKey string `json:"secret_key"` // secret reference, defaults to env:AWS_SECRET_ACCESS_KEY
+	PathStyle bool   `json:"path_style"` // required by MinIO
+}
+
+// TLSConfig controls certificate provisioning for production nginx
+type TLSConfig struct {
+	Mode            string `json:"mode"`              // "self_signed", "local_ca" or "acme"
+	CertDir         string `json:"cert_dir"`          // defaults to <state dir>/certs
+	RenewBeforeDays int    `json:"renew_before_days"` // defaults to 30
+	RenewInterval   string `json:"renew_interval"`    // Go duration, defaults to 12h
+	ACMEDirectory   string `json:"acme_directory"`    // e.g. https://pebble:14000/dir
+	ACMEEmail       string `json:"acme_email"`
+	ACMECABundle    string `json:"acme_ca_bundle"` // CA bundle to trust a private ACME server
+	HTTPPort        int    `json:"http_port"`      // standalone challenge port, defaults to 80
 }
 
 type InstanceSite struct {
 	SiteName string   `json:"site_name"`
 	Apps     []string `json:"apps"`
+
+	// PreviousNames are former names of the site; a site found under one of them is renamed
+	PreviousNames []string `json:"previous_names"`
+
+	// Site creation options, mapped to `bench new-site` flags
+	AdminPassword       string   `json:"admin_password"`
+	AdminPasswordSecret string   `json:"admin_password_secret"` // env:NAME, file:/path or secret:key
+	DBName              string   `json:"db_name"`
+	DBType              string   `json:"db_type"`
+	DBHost              string   `json:"db_host"`
+	InstallApps         []string `json:"install_apps"`
+	SetDefault          bool     `json:"set_default"`
+
+	// SiteConfig keys reconciled into the site's site_config.json on every run
+	SiteConfig map[string]any `json:"site_config"`
+	// PruneSiteConfig removes keys goftw previously managed once they leave SiteConfig
+	PruneSiteConfig bool `json:"prune_site_config"`
+
+	// Domains are additional hostnames served by the site besides its name
+	Domains []string `json:"domains"`
+
+	// Nginx holds per-site additions to the generated nginx server block
+	Nginx NginxSiteConfig `json:"nginx"`
+
+	// Backup schedules backups of the site and their retention
+	Backup BackupConfig `json:"backup"`
+
+	// RestoreFrom creates a missing site from a backup instead of a blank site:
+	// a local backup directory, a catalog ID (site/timestamp) or "s3:<key prefix>"
+	RestoreFrom string `json:"restore_from"`
+
+	// CloneFrom creates a missing site as a copy of another site of this bench
+	CloneFrom string `json:"clone_from"`
+
+	// SchedulerEnabled enables or disables the site's scheduler; unset leaves it alone
+	SchedulerEnabled *bool `json:"scheduler_enabled"`
+}
+
+type BackupConfig struct {
+	Schedule   string `json:"schedule"`     // cron expression, e.g. "0 2 * * *"
+	SkipFiles  bool   `json:"skip_files"`   // back up the database only
+	KeepLast   int    `json:"keep_last"`    // defaults to backup_limit from common_site_config.json
+	MaxAgeDays int    `json:"max_age_days"` // 0 keeps backups regardless of age
+	// RemotePrefix is the site's key prefix in object storage, defaults to the site name
+	RemotePrefix string `json:"remote_prefix"`
+}
+
+type NginxSiteConfig struct {
+	ClientMaxBodySize string   `json:"client_max_body_size"` // defaults to 50m
+	RateLimit         string   `json:"rate_limit"`           // e.g. "10r/s"
+	RateLimitBurst    int      `json:"rate_limit_burst"`
+	ExtraLocations    []string `json:"extra_locations"` // raw location blocks
+	Snippet           string   `json:"snippet"`         // raw directives appended to the server block
 }
 
 type CommonConfig struct {
 	RedisQueue    string `json:"redis_queue"`
 	RedisCache    string `json:"redis_cache"`
 	RedisSocketIO string `json:"redis_socketio"`
+	SocketIOPort  int    `json:"socketio_port"`
+	WebserverPort int    `json:"webserver_port"`
+	HTTPTimeout   int    `json:"http_timeout"`
+	BackupLimit   int    `json:"backup_limit"`
+	MaxWorkers    int    `json:"max_workers"`
 }
 
 // LoadInstance loads and parses instance.json
@@ -30,6 +201,11 @@ func LoadInstance(path string) (*InstanceConfig, error) {
 	if err != nil {
 		return nil, err
 	}
+	return ParseInstance(data)
+}
+
+// ParseInstance parses instance.json content and applies its defaults
+func ParseInstance(data []byte) (*InstanceConfig, error) {
 	var cfg InstanceConfig
 	if err := json.Unmarshal(data, &cfg); err != nil {
 		return nil, err
@@ -40,9 +216,54 @@ func LoadInstance(path string) (*InstanceConfig, error) {
 	if cfg.Deployment == "" {
 		cfg.Deployment = "develop"
 	}
+	if err := cfg.resolveLocalApps(); err != nil {
+		return nil, err
+	}
 	return &cfg, nil
 }
 
+// IsLocalAppPath reports whether an apps entry is the path of a local development app instead of an app name
+func IsLocalAppPath(entry string) bool {
+	return strings.HasPrefix(entry, "/") || strings.HasPrefix(entry, "./") || strings.HasPrefix(entry, "../")
+}
+
+// LocalAppPath resolves a local app path from instance.json. "./mount/..." is the compose
+// volume mounted at the frappe home; other relative paths start at instance.json's directory.
+func LocalAppPath(ref string) string {
+	if rest, ok := strings.CutPrefix(ref, "./mount/"); ok {
+		return filepath.Join(environ.GetFrappeHome(), rest)
+	}
+	if filepath.IsAbs(ref) {
+		return filepath.Clean(ref)
+	}
+	return filepath.Join(environ.GetInstanceDir(), ref)
+}
+
+// resolveLocalApps replaces local app paths in the sites' apps by the app names (the last
+// path element) and records the paths in LocalApps.
+func (cfg *InstanceConfig) resolveLocalApps() error {
+	cfg.LocalApps = map[string]string{}
+	for i := range cfg.InstanceSites {
+		site := &cfg.InstanceSites[i]
+		for _, apps := range []*[]string{&site.Apps, &site.InstallApps} {
+			for j, entry := range *apps {
+				if !IsLocalAppPath(entry) {
+					continue
+				}
+				name := filepath.Base(filepath.Clean(entry))
+				// The same directory may be spelled differently, e.g. with a trailing slash
+				if path, ok := cfg.LocalApps[name]; !ok {
+					cfg.LocalApps[name] = entry
+				} else if LocalAppPath(path) != LocalAppPath(entry) {
+					return fmt.Errorf("app %s is declared from both %s and %s", name, path, entry)
+				}
+				(*apps)[j] = name
+			}
+		}
+	}
+	return nil
+}
+
 // LoadCommonSitesConfig loads and parses common_site_config.json
 func LoadCommonSitesConfig(path string) (*CommonConfig, error) {
 	data, err := os.ReadFile(path)
@@ -53,5 +274,14 @@ func LoadCommonSitesConfig(path string) (*CommonConfig, error) {
 	if err := json.Unmarshal(data, &cfg); err != nil {
 		return nil, err
 	}
+	if cfg.SocketIOPort == 0 {
+		cfg.SocketIOPort = 9000
+	}
+	if cfg.WebserverPort == 0 {
+		cfg.WebserverPort = 8000
+	}
+	if cfg.HTTPTimeout == 0 {
+		cfg.HTTPTimeout = 120
+	}
 	return &cfg, nil
 }
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveLocalApps(t *testing.T) {
+	tests := []struct {
+		name      string
+		json      string
+		apps      []string
+		install   []string
+		localApps map[string]string
+		wantErr   string
+	}{
+		{
+			name:      "app names only",
+			json:      `{"instance_sites": [{"site_name": "a.local", "apps": ["frappe", "erpnext"]}]}`,
+			apps:      []string{"frappe", "erpnext"},
+			localApps: map[string]string{},
+		},
+		{
+			name:      "absolute and relative paths",
+			json:      `{"instance_sites": [{"site_name": "a.local", "apps": ["frappe", "/workspace/my_app/", "./apps/other"], "install_apps": ["../third"]}]}`,
+			apps:      []string{"frappe", "my_app", "other"},
+			install:   []string{"third"},
+			localApps: map[string]string{"my_app": "/workspace/my_app/", "other": "./apps/other", "third": "../third"},
+		},
+		{
+			name: "same path on two sites",
+			json: `{"instance_sites": [
+				{"site_name": "a.local", "apps": ["/workspace/my_app"]},
+				{"site_name": "b.local", "apps": ["/workspace/my_app"]}]}`,
+			apps:      []string{"my_app"},
+			localApps: map[string]string{"my_app": "/workspace/my_app"},
+		},
+		{
+			name: "same directory spelled differently",
+			json: `{"instance_sites": [
+				{"site_name": "a.local", "apps": ["/workspace/my_app/", "./apps/other"]},
+				{"site_name": "b.local", "apps": ["/workspace//my_app", "./apps/../apps/other/"]}]}`,
+			apps:      []string{"my_app", "other"},
+			localApps: map[string]string{"my_app": "/workspace/my_app/", "other": "./apps/other"},
+		},
+		{
+			name: "one app from two paths",
+			json: `{"instance_sites": [
+				{"site_name": "a.local", "apps": ["/workspace/my_app"]},
+				{"site_name": "b.local", "apps": ["/other/my_app"]}]}`,
+			wantErr: "app my_app is declared from both /workspace/my_app and /other/my_app",
+		},
+	}
+	for _, tt := range tests {
+		cfg, err := ParseInstance([]byte(tt.json))
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		site := cfg.InstanceSites[0]
+		if !reflect.DeepEqual(site.Apps, tt.apps) {
+			t.Errorf("%s: apps = %v, want %v", tt.name, site.Apps, tt.apps)
+		}
+		if !reflect.DeepEqual(site.InstallApps, tt.install) {
+			t.Errorf("%s: install_apps = %v, want %v", tt.name, site.InstallApps, tt.install)
+		}
+		if !reflect.DeepEqual(cfg.LocalApps, tt.localApps) {
+			t.Errorf("%s: local apps = %v, want %v", tt.name, cfg.LocalApps, tt.localApps)
+		}
+	}
+}
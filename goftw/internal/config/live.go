@@ -0,0 +1,39 @@
+package config
+
+import "sync/atomic"
+
+// Live holds the configs in effect while goftw runs. Hot reload publishes new configs
+// with SetInstance and SetCommon, and loops running in other goroutines read them again
+// on every tick. A published config is never modified, only replaced.
+type Live struct {
+	instance atomic.Pointer[InstanceConfig]
+	common   atomic.Pointer[CommonConfig]
+}
+
+// NewLive publishes the configs loaded at startup
+func NewLive(instanceCfg *InstanceConfig, commonCfg *CommonConfig) *Live {
+	l := &Live{}
+	l.instance.Store(instanceCfg)
+	l.common.Store(commonCfg)
+	return l
+}
+
+// Instance returns the instance.json in effect
+func (l *Live) Instance() *InstanceConfig {
+	return l.instance.Load()
+}
+
+// Common returns the common_site_config.json in effect
+func (l *Live) Common() *CommonConfig {
+	return l.common.Load()
+}
+
+// SetInstance publishes a new instance.json
+func (l *Live) SetInstance(cfg *InstanceConfig) {
+	l.instance.Store(cfg)
+}
+
+// SetCommon publishes a new common_site_config.json
+func (l *Live) SetCommon(cfg *CommonConfig) {
+	l.common.Store(cfg)
+}
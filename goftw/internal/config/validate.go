@@ -0,0 +1,164 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"goftw/internal/cron"
+)
+
+// siteNamePattern matches the hostnames frappe accepts as site names
+var siteNamePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9.-]*[A-Za-z0-9])?$`)
+
+// Validate checks instance.json for mistakes that would otherwise surface halfway
+// through a reconciliation run. All problems are reported at once.
+func (cfg *InstanceConfig) Validate() error {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	switch cfg.OnError {
+	case "", "fail_fast", "continue":
+	default:
+		add("on_error must be fail_fast or continue, got %q", cfg.OnError)
+	}
+	switch cfg.AbandonedSites.Policy {
+	case "", "drop", "archive":
+	default:
+		add("abandoned_sites.policy must be drop or archive, got %q", cfg.AbandonedSites.Policy)
+	}
+	if cfg.AbandonedSites.GracePeriodDays < 0 {
+		add("abandoned_sites.grace_period_days must not be negative")
+	}
+	if w := cfg.Workers; w.Gunicorn < 0 || w.Short < 0 || w.Default < 0 || w.Long < 0 {
+		add("workers counts must not be negative")
+	}
+	for app, p := range cfg.Updates.Apps {
+		if !validPolicy(p.Policy) {
+			add("updates.apps.%s.policy must be track, pinned or manual, got %q", app, p.Policy)
+		}
+		policy := p.Policy
+		if policy == "" {
+			policy = cfg.Updates.DefaultPolicy
+		}
+		if p.Ref != "" && policy != "pinned" {
+			add("updates.apps.%s.ref is only used with the pinned policy", app)
+		}
+	}
+	if !validPolicy(cfg.Updates.DefaultPolicy) {
+		add("updates.default_policy must be track, pinned or manual, got %q", cfg.Updates.DefaultPolicy)
+	}
+	for i, w := range cfg.Updates.Windows {
+		if _, err := cron.Parse(w.Schedule); err != nil {
+			add("updates.windows[%d].schedule: %v", i, err)
+		}
+		if d, err := time.ParseDuration(w.Duration); err != nil || d <= 0 {
+			add("updates.windows[%d].duration must be a positive duration, got %q", i, w.Duration)
+		}
+	}
+	if cfg.MaxParallelSites < 0 {
+		add("max_parallel_sites must not be negative")
+	}
+	if cfg.TLS != nil {
+		switch cfg.TLS.Mode {
+		case "self_signed", "local_ca", "acme":
+		default:
+			add("tls.mode must be self_signed, local_ca or acme, got %q", cfg.TLS.Mode)
+		}
+		if cfg.TLS.RenewInterval != "" {
+			if d, err := time.ParseDuration(cfg.TLS.RenewInterval); err != nil || d <= 0 {
+				add("tls.renew_interval must be a positive duration, got %q", cfg.TLS.RenewInterval)
+			}
+		}
+	}
+	if cfg.Watch != nil && cfg.Watch.Interval != "" {
+		if d, err := time.ParseDuration(cfg.Watch.Interval); err != nil || d <= 0 {
+			add("watch.interval must be a positive duration, got %q", cfg.Watch.Interval)
+		}
+	}
+
+	if cfg.Mirrors != nil && cfg.Mirrors.RefreshInterval != "" {
+		if d, err := time.ParseDuration(cfg.Mirrors.RefreshInterval); err != nil || d <= 0 {
+			add("mirrors.refresh_interval must be a positive duration, got %q", cfg.Mirrors.RefreshInterval)
+		}
+	}
+
+	seen := map[string]bool{}
+	previous := map[string]string{}
+	for _, site := range cfg.InstanceSites {
+		for _, old := range site.PreviousNames {
+			if other, ok := previous[old]; ok && other != site.SiteName {
+				add("previous name %s is claimed by sites %s and %s", old, other, site.SiteName)
+			}
+			previous[old] = site.SiteName
+		}
+	}
+	for i, site := range cfg.InstanceSites {
+		if owner, ok := previous[site.SiteName]; ok {
+			add("site %s is also listed as a previous name of site %s", site.SiteName, owner)
+		}
+		if !siteNamePattern.MatchString(site.SiteName) {
+			add("instance_sites[%d]: invalid site_name %q", i, site.SiteName)
+			continue
+		}
+		if seen[site.SiteName] {
+			add("site %s is listed more than once", site.SiteName)
+		}
+		seen[site.SiteName] = true
+		if site.Backup.Schedule != "" {
+			if _, err := cron.Parse(site.Backup.Schedule); err != nil {
+				add("site %s: backup.schedule: %v", site.SiteName, err)
+			}
+		}
+		if site.CloneFrom == site.SiteName {
+			add("site %s cannot be cloned from itself", site.SiteName)
+		}
+		if site.CloneFrom != "" && site.RestoreFrom != "" {
+			add("site %s sets both clone_from and restore_from", site.SiteName)
+		}
+		for _, old := range site.PreviousNames {
+			if !siteNamePattern.MatchString(old) {
+				add("site %s: invalid previous name %q", site.SiteName, old)
+			}
+		}
+		for _, domain := range site.Domains {
+			if !siteNamePattern.MatchString(domain) {
+				add("site %s: invalid domain %q", site.SiteName, domain)
+			}
+		}
+	}
+
+	// Chained clones are reconciled in order, which a cycle makes impossible
+	cloneFrom := map[string]string{}
+	for _, site := range cfg.InstanceSites {
+		if site.CloneFrom != "" && site.CloneFrom != site.SiteName {
+			cloneFrom[site.SiteName] = site.CloneFrom
+		}
+	}
+	for _, site := range cfg.InstanceSites {
+		visited := map[string]bool{site.SiteName: true}
+		for src, ok := cloneFrom[site.SiteName]; ok; src, ok = cloneFrom[src] {
+			if visited[src] {
+				add("site %s: clone_from forms a cycle through %s", site.SiteName, src)
+				break
+			}
+			visited[src] = true
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid instance.json: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func validPolicy(p string) bool {
+	switch p {
+	case "", "track", "pinned", "manual":
+		return true
+	}
+	return false
+}
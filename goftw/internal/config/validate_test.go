@@ -0,0 +1,75 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want []string // substrings of the error, none for a valid config
+	}{
+		{"minimal", `{"instance_sites": [{"site_name": "a.local"}]}`, nil},
+		{"full", `{
+			"on_error": "continue",
+			"abandoned_sites": {"policy": "archive", "grace_period_days": 7},
+			"workers": {"gunicorn": 4, "short": 2},
+			"updates": {"default_policy": "manual", "apps": {"erpnext": {"policy": "pinned", "ref": "v15.0.0"}},
+				"windows": [{"schedule": "0 2 * * 1-5", "duration": "2h"}]},
+			"tls": {"mode": "acme", "renew_interval": "24h"},
+			"watch": {"interval": "10m"},
+			"mirrors": {"refresh_interval": "1h"},
+			"instance_sites": [
+				{"site_name": "a.local", "domains": ["www.a.local"], "backup": {"schedule": "@daily"}},
+				{"site_name": "b.local", "clone_from": "a.local", "previous_names": ["old.local"]},
+				{"site_name": "c.local", "clone_from": "b.local"}]}`, nil},
+		{"bad enums", `{"on_error": "retry", "abandoned_sites": {"policy": "keep"}, "tls": {"mode": "manual"}}`,
+			[]string{"on_error must be", "abandoned_sites.policy must be", "tls.mode must be"}},
+		{"negative counts", `{"abandoned_sites": {"grace_period_days": -1}, "workers": {"long": -1}, "max_parallel_sites": -2}`,
+			[]string{"grace_period_days must not be negative", "workers counts must not be negative", "max_parallel_sites must not be negative"}},
+		{"update policies", `{"updates": {"default_policy": "latest", "apps": {"hrms": {"policy": "track", "ref": "v1"}, "crm": {"policy": "never"}}}}`,
+			[]string{"updates.default_policy must be", "updates.apps.crm.policy must be", "updates.apps.hrms.ref is only used with the pinned policy"}},
+		{"update windows", `{"updates": {"windows": [{"schedule": "0 25 * * *", "duration": "1h"}, {"schedule": "@daily", "duration": "0s"}]}}`,
+			[]string{"updates.windows[0].schedule", "updates.windows[1].duration must be a positive duration"}},
+		{"intervals", `{"tls": {"mode": "self_signed", "renew_interval": "-1h"}, "watch": {"interval": "0s"}, "mirrors": {"refresh_interval": "soon"}}`,
+			[]string{"tls.renew_interval must be a positive duration", "watch.interval must be a positive duration", "mirrors.refresh_interval must be a positive duration"}},
+		{"site names", `{"instance_sites": [{"site_name": "a.local"}, {"site_name": "a.local"}, {"site_name": "-bad"},
+			{"site_name": "b.local", "domains": ["bad domain"], "previous_names": ["a.local", "bad_name"]}]}`,
+			[]string{"site a.local is listed more than once", `invalid site_name "-bad"`, `invalid domain "bad domain"`,
+				`invalid previous name "bad_name"`, "site a.local is also listed as a previous name of site b.local"}},
+		{"previous name claimed twice", `{"instance_sites": [{"site_name": "a.local", "previous_names": ["old.local"]},
+			{"site_name": "b.local", "previous_names": ["old.local"]}]}`,
+			[]string{"previous name old.local is claimed by sites a.local and b.local"}},
+		{"site sources", `{"instance_sites": [{"site_name": "a.local", "clone_from": "a.local"},
+			{"site_name": "b.local", "clone_from": "a.local", "restore_from": "s3:backups/a"},
+			{"site_name": "c.local", "backup": {"schedule": "daily"}}]}`,
+			[]string{"site a.local cannot be cloned from itself", "site b.local sets both clone_from and restore_from", "site c.local: backup.schedule"}},
+		{"clone cycle", `{"instance_sites": [{"site_name": "a.local", "clone_from": "c.local"},
+			{"site_name": "b.local", "clone_from": "a.local"}, {"site_name": "c.local", "clone_from": "b.local"}]}`,
+			[]string{"site a.local: clone_from forms a cycle", "site b.local: clone_from forms a cycle", "site c.local: clone_from forms a cycle"}},
+	}
+	for _, tt := range tests {
+		cfg, err := ParseInstance([]byte(tt.json))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		err = cfg.Validate()
+		if len(tt.want) == 0 {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: Validate succeeded, want %v", tt.name, tt.want)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: error lacks %q: %v", tt.name, want, err)
+			}
+		}
+	}
+}
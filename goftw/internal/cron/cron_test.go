@@ -0,0 +1,130 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRejects(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"1,x * * * *",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	// 2024-01-15 is a Monday, 2024-01-14 a Sunday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, time.January, day, hour, minute, 30, 0, time.UTC)
+	}
+	tests := []struct {
+		expr string
+		at   time.Time
+		want bool
+	}{
+		{"* * * * *", at(15, 12, 34), true},
+		{"30 2 * * *", at(15, 2, 30), true},
+		{"30 2 * * *", at(15, 2, 31), false},
+		{"30 2 * * *", at(15, 3, 30), false},
+
+		// ranges
+		{"0 9-17 * * *", at(15, 9, 0), true},
+		{"0 9-17 * * *", at(15, 17, 0), true},
+		{"0 9-17 * * *", at(15, 18, 0), false},
+		{"0 0 * * 1-5", at(15, 0, 0), true},
+		{"0 0 * * 1-5", at(14, 0, 0), false},
+
+		// steps
+		{"*/15 * * * *", at(15, 1, 45), true},
+		{"*/15 * * * *", at(15, 1, 50), false},
+		{"5/20 * * * *", at(15, 1, 25), true},
+		{"5/20 * * * *", at(15, 1, 20), false},
+		{"0-30/10 * * * *", at(15, 1, 30), true},
+		{"0-30/10 * * * *", at(15, 1, 40), false},
+
+		// lists
+		{"1,15,30 * * * *", at(15, 1, 15), true},
+		{"1,15,30 * * * *", at(15, 1, 16), false},
+		{"0 1,13-14 * * *", at(15, 14, 0), true},
+		{"0 1,13-14 * * *", at(15, 12, 0), false},
+
+		// 0 and 7 both mean Sunday
+		{"0 0 * * 0", at(14, 0, 0), true},
+		{"0 0 * * 7", at(14, 0, 0), true},
+		{"0 0 * * 7", at(15, 0, 0), false},
+
+		// a restricted day of month and day of week match if either one does
+		{"0 0 1 * 1", at(15, 0, 0), true},
+		{"0 0 1 * 1", at(1, 0, 0), true},
+		{"0 0 1 * 1", at(14, 0, 0), false},
+		{"0 0 15 * 0", at(14, 0, 0), true},
+		// with either one unrestricted, both must match
+		{"0 0 15 * *", at(15, 0, 0), true},
+		{"0 0 15 * *", at(14, 0, 0), false},
+		{"0 0 * * 0", at(15, 0, 0), false},
+
+		// months and aliases
+		{"0 0 * 2 *", at(15, 0, 0), false},
+		{"@daily", at(15, 0, 0), true},
+		{"@daily", at(15, 0, 1), false},
+		{"@hourly", at(15, 7, 0), true},
+		{"@weekly", at(14, 0, 0), true},
+		{"@monthly", at(1, 0, 0), true},
+		{"@monthly", at(15, 0, 0), false},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.expr, err)
+		}
+		if got := s.Matches(tt.at); got != tt.want {
+			t.Errorf("%q matches %s = %v, want %v", tt.expr, tt.at.Format("Mon 2006-01-02 15:04"), got, tt.want)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	from := time.Date(2024, time.January, 15, 10, 7, 12, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.January, 15, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.January, 15, 10, 15, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2024, time.January, 16, 2, 0, 0, 0, time.UTC)},
+		{"0 0 1 3 *", time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.expr, err)
+		}
+		got, ok := s.Next(from)
+		if !ok || !got.Equal(tt.want) {
+			t.Errorf("%q next after %s = %s (%v), want %s", tt.expr, from, got, ok, tt.want)
+		}
+	}
+
+	// February 30th never comes
+	s, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := s.Next(from); ok {
+		t.Errorf("\"0 0 30 2 *\" next = %s, want none", got)
+	}
+}
@@ -24,18 +24,7 @@ func WaitForDB(cfg Config) error {
 
 	fmt.Printf("[Database] Waiting for MariaDB at %s:%s...\n", cfg.Host, cfg.Port)
 	for {
-		_, err := whoami.RunSwallowIO(
-			"mysqladmin",
-			"ping",
-			"-h", cfg.Host,
-			"-P", cfg.Port,
-			"-u", cfg.User,
-			fmt.Sprintf("-p%s", cfg.Password),
-			"--silent",
-		)
-		// if err != nil {
-		// 	return err
-		// }
+		err := Ping(cfg)
 		if err == nil {
 			fmt.Println("[OK] MariaDB reachable.")
 			return nil
@@ -46,3 +35,17 @@ func WaitForDB(cfg Config) error {
 		time.Sleep(2 * time.Second)
 	}
 }
+
+// Ping checks once whether the database answers
+func Ping(cfg Config) error {
+	_, err := whoami.RunSwallowIO(
+		"mysqladmin",
+		"ping",
+		"-h", cfg.Host,
+		"-P", cfg.Port,
+		"-u", cfg.User,
+		fmt.Sprintf("-p%s", cfg.Password),
+		"--silent",
+	)
+	return err
+}
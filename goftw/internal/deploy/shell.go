@@ -12,6 +12,12 @@ func DeployThroughShell(deployMode string) {
 	os.Setenv("DEPLOYMENT", deployMode)
 	os.Setenv("MERGED_SUPERVISOR_CONF", "/supervisor-merged.conf")
 	os.Setenv("WRAPPER_CONF", "/supervisor.conf")
+	// nginx config is rendered by goftw, the script only has to start services
+	os.Setenv("NGINX_CONF_MANAGED", "1")
+	// so is the bench's supervisor config, with the declared worker counts
+	if deployMode == "production" {
+		os.Setenv("SUPERVISOR_CONF_MANAGED", "1")
+	}
 
 	whoami.RunPrintIO("bash", "/scripts/service.sh")
 }
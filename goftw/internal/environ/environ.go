@@ -1,11 +1,19 @@
 package environ
 
-import "os"
+import (
+	"os"
+	"path/filepath"
+)
 
 var (
 	frappeHome        = os.Getenv("FRAPPE_HOME")
 	instanceFile      = os.Getenv("INSTANCE_JSON_SOURCE")
 	commonSitesConfig = os.Getenv("COMMON_CONFIG_SOURCE")
+	stateDir          = os.Getenv("GOFTW_STATE_DIR")
+	secretsFile       = os.Getenv("GOFTW_SECRETS_FILE")
+	backupDir         = os.Getenv("GOFTW_BACKUP_DIR")
+	mirrorDir         = os.Getenv("GOFTW_MIRROR_DIR")
+	bundleFile        = os.Getenv("GOFTW_BUNDLE")
 )
 
 // Helper to read env with default
@@ -20,7 +28,7 @@ func GetEnv(key, def string) string {
 func GetFrappeHome() string {
 
 	if frappeHome == "" {
-		frappeHome = "/home/frappe"
+		return "/home/frappe"
 	}
 	return frappeHome
 }
@@ -43,15 +51,62 @@ func GetBenchPath() string {
 // GetInstanceFile returns the path to the instance.json file, defaulting to /instance.json.
 func GetInstanceFile() string {
 	if instanceFile == "" {
-		instanceFile = "/instance.json"
+		return "/instance.json"
 	}
 	return instanceFile
 }
 
+// InstanceDirEnv passes the directory of the user's instance.json to worker processes, whose
+// INSTANCE_JSON_SOURCE points at the copy pinned by hot reload instead
+const InstanceDirEnv = "GOFTW_INSTANCE_DIR"
+
+// GetInstanceDir returns the directory relative paths in instance.json start at, defaulting
+// to the directory of the instance.json file.
+func GetInstanceDir() string {
+	return GetEnv(InstanceDirEnv, filepath.Dir(GetInstanceFile()))
+}
+
 // GetCommonSitesConfigPath returns the path to the common_site_config.json file, defaulting to /common_site_config.json.
 func GetCommonSitesConfigPath() string {
 	if commonSitesConfig == "" {
-		commonSitesConfig = "/common_site_config.json"
+		return "/common_site_config.json"
 	}
 	return commonSitesConfig
 }
+
+// GetStatePath returns the directory goftw keeps its own state in, defaulting to <frappe home>/.goftw.
+func GetStatePath() string {
+	if stateDir == "" {
+		return GetFrappeHome() + "/.goftw"
+	}
+	return stateDir
+}
+
+// GetSecretsFile returns the path to the local secrets file, defaulting to <state dir>/secrets.json.
+func GetSecretsFile() string {
+	if secretsFile == "" {
+		return GetStatePath() + "/secrets.json"
+	}
+	return secretsFile
+}
+
+// GetBackupPath returns the directory site backups are written to, defaulting to <frappe home>/backups.
+func GetBackupPath() string {
+	if backupDir == "" {
+		return GetFrappeHome() + "/backups"
+	}
+	return backupDir
+}
+
+// GetMirrorPath returns the directory bare git mirrors of apps are kept in, defaulting to <frappe home>/git-mirrors.
+func GetMirrorPath() string {
+	if mirrorDir == "" {
+		return GetFrappeHome() + "/git-mirrors"
+	}
+	return mirrorDir
+}
+
+// GetBundleFile returns the offline bundle a new bench is imported from, empty to run bench init.
+func GetBundleFile() string {
+	return bundleFile
+}
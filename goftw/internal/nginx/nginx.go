@@ -0,0 +1,106 @@
+package nginx
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"text/template"
+
+	"goftw/internal/sudo"
+)
+
+// Conf is the data the nginx template is rendered from
+type Conf struct {
+	BenchName     string
+	SitesPath     string
+	WebserverPort int
+	SocketIOPort  int
+	HTTPPort      int
+	HTTPTimeout   int
+	ACMEWebroot   string // serve ACME challenges from here when set
+	Sites         []Site
+}
+
+// Site is one frappe site served by nginx
+type Site struct {
+	Name              string
+	ServerNames       []string
+	SSLCertificate    string
+	SSLCertificateKey string
+	ClientMaxBodySize string
+	RateLimit         string // e.g. "10r/s"
+	RateLimitBurst    int
+	ExtraLocations    []string
+	Snippet           string
+}
+
+var zoneChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// Zone is the limit_req zone name used for the site
+func (s Site) Zone() string {
+	return "goftw_" + zoneChars.ReplaceAllString(s.Name, "_")
+}
+
+var confTmpl = template.Must(template.New("nginx.conf").Parse(confTemplate))
+
+// Render renders the nginx config for the bench
+func Render(conf Conf) ([]byte, error) {
+	var out bytes.Buffer
+	if err := confTmpl.Execute(&out, conf); err != nil {
+		return nil, fmt.Errorf("failed to render nginx config: %v", err)
+	}
+	return out.Bytes(), nil
+}
+
+// Apply swaps rendered into confPath, symlinks it to linkPath and validates it with `nginx -t`.
+// When validation fails the previous config is restored. A running nginx is reloaded on success.
+func Apply(rendered []byte, confPath, linkPath string) error {
+	backupPath := confPath + ".bak"
+	newPath := confPath + ".new"
+
+	previous, err := os.ReadFile(confPath)
+	hadPrevious := err == nil
+	if hadPrevious {
+		if err := os.WriteFile(backupPath, previous, 0644); err != nil {
+			return fmt.Errorf("failed to back up %s: %v", confPath, err)
+		}
+	}
+
+	if err := os.WriteFile(newPath, rendered, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", newPath, err)
+	}
+	if err := os.Rename(newPath, confPath); err != nil {
+		return fmt.Errorf("failed to swap in %s: %v", confPath, err)
+	}
+	if err := sudo.RunPrintIO("ln", "-sf", confPath, linkPath); err != nil {
+		return fmt.Errorf("failed to symlink nginx config: %v", err)
+	}
+
+	if err := sudo.RunPrintIO("nginx", "-t"); err != nil {
+		fmt.Printf("[NGINX] Validation failed, rolling back %s\n", confPath)
+		if hadPrevious {
+			if rbErr := os.Rename(backupPath, confPath); rbErr != nil {
+				return fmt.Errorf("nginx -t failed (%v) and rollback failed: %v", err, rbErr)
+			}
+		} else {
+			_ = os.Remove(confPath)
+			_ = sudo.RemoveFile(linkPath)
+		}
+		return fmt.Errorf("nginx -t failed: %v", err)
+	}
+
+	if Running() {
+		if err := sudo.RunPrintIO("nginx", "-s", "reload"); err != nil {
+			return fmt.Errorf("nginx reload failed: %v", err)
+		}
+		fmt.Println("[NGINX] Reloaded")
+	}
+	return nil
+}
+
+// Running reports whether an nginx master process is up
+func Running() bool {
+	_, err := sudo.RunSwallowIO("pgrep", "-x", "nginx")
+	return err == nil
+}
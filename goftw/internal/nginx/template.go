@@ -0,0 +1,157 @@
+package nginx
+
+// confTemplate mirrors the server blocks `bench setup nginx` produces, with
+// per-site snippets, rate limits and ACME challenge handling added.
+const confTemplate = `# Generated by goftw. Changes are overwritten on every run.
+upstream {{ .BenchName }}-frappe {
+	server 127.0.0.1:{{ .WebserverPort }} fail_timeout=0;
+}
+
+upstream {{ .BenchName }}-socketio-server {
+	server 127.0.0.1:{{ .SocketIOPort }} fail_timeout=0;
+}
+{{ range .Sites }}{{ if .RateLimit }}
+limit_req_zone $binary_remote_addr zone={{ .Zone }}:10m rate={{ .RateLimit }};
+{{ end }}{{ end }}
+{{- range $site := .Sites }}
+# {{ $site.Name }}
+server {
+	{{- if $site.SSLCertificate }}
+	listen 443 ssl;
+	ssl_certificate {{ $site.SSLCertificate }};
+	ssl_certificate_key {{ $site.SSLCertificateKey }};
+	ssl_session_timeout 5m;
+	ssl_session_cache shared:SSL:10m;
+	ssl_session_tickets off;
+	ssl_protocols TLSv1.2 TLSv1.3;
+	ssl_prefer_server_ciphers off;
+	{{- else }}
+	listen {{ $.HTTPPort }};
+	{{- end }}
+	server_name {{ range $site.ServerNames }}{{ . }} {{ end }};
+
+	root {{ $.SitesPath }};
+
+	add_header X-Frame-Options "SAMEORIGIN";
+	add_header Strict-Transport-Security "max-age=63072000; includeSubDomains; preload";
+	add_header X-Content-Type-Options nosniff;
+	add_header X-XSS-Protection "1; mode=block";
+	add_header Referrer-Policy "same-origin, strict-origin-when-cross-origin";
+
+	location /assets {
+		try_files $uri =404;
+		add_header Cache-Control "max-age=31536000";
+	}
+
+	location ~ ^/protected/(.*) {
+		internal;
+		try_files /{{ $site.Name }}/$1 =404;
+	}
+
+	location /socket.io {
+		proxy_http_version 1.1;
+		proxy_set_header Upgrade $http_upgrade;
+		proxy_set_header Connection "upgrade";
+		proxy_set_header X-Frappe-Site-Name {{ $site.Name }};
+		proxy_set_header Origin $scheme://$http_host;
+		proxy_set_header Host $host;
+
+		proxy_pass http://{{ $.BenchName }}-socketio-server;
+	}
+	{{- if $.ACMEWebroot }}
+
+	location ^~ /.well-known/acme-challenge/ {
+		root {{ $.ACMEWebroot }};
+	}
+	{{- end }}
+
+	location / {
+		rewrite ^(.+)/$ $1 permanent;
+		rewrite ^(.+)/index\.html$ $1 permanent;
+		rewrite ^(.+)\.html$ $1 permanent;
+
+		location ~* ^/files/.*.(htm|html|svg|xml) {
+			add_header Content-disposition "attachment";
+			try_files /{{ $site.Name }}/public/$uri @webserver;
+		}
+
+		try_files /{{ $site.Name }}/public/$uri @webserver;
+	}
+
+	location @webserver {
+		{{- if $site.RateLimit }}
+		limit_req zone={{ $site.Zone }}{{ if $site.RateLimitBurst }} burst={{ $site.RateLimitBurst }} nodelay{{ end }};
+		{{- end }}
+		proxy_http_version 1.1;
+		proxy_set_header X-Forwarded-For $remote_addr;
+		proxy_set_header X-Forwarded-Proto $scheme;
+		proxy_set_header X-Frappe-Site-Name {{ $site.Name }};
+		proxy_set_header Host $host;
+		proxy_set_header X-Use-X-Accel-Redirect True;
+		proxy_read_timeout {{ $.HTTPTimeout }};
+		proxy_redirect off;
+
+		proxy_pass http://{{ $.BenchName }}-frappe;
+	}
+	{{- range $site.ExtraLocations }}
+
+	{{ . }}
+	{{- end }}
+
+	access_log /var/log/nginx/access.log;
+	error_log /var/log/nginx/error.log;
+
+	# optimizations
+	sendfile on;
+	keepalive_timeout 15;
+	client_max_body_size {{ $site.ClientMaxBodySize }};
+	client_body_buffer_size 16K;
+	client_header_buffer_size 1k;
+
+	# enable gzip compresion
+	gzip on;
+	gzip_http_version 1.1;
+	gzip_comp_level 5;
+	gzip_min_length 256;
+	gzip_proxied any;
+	gzip_vary on;
+	gzip_types
+		application/atom+xml
+		application/javascript
+		application/json
+		application/rss+xml
+		application/vnd.ms-fontobject
+		application/x-font-ttf
+		application/font-woff
+		application/x-web-app-manifest+json
+		application/xhtml+xml
+		application/xml
+		font/opentype
+		image/svg+xml
+		image/x-icon
+		text/css
+		text/plain
+		text/x-component;
+	{{- if $site.Snippet }}
+
+	{{ $site.Snippet }}
+	{{- end }}
+}
+{{- if $site.SSLCertificate }}
+
+server {
+	listen {{ $.HTTPPort }};
+	server_name {{ range $site.ServerNames }}{{ . }} {{ end }};
+	{{- if $.ACMEWebroot }}
+
+	location ^~ /.well-known/acme-challenge/ {
+		root {{ $.ACMEWebroot }};
+	}
+	{{- end }}
+
+	location / {
+		return 301 https://$host$request_uri;
+	}
+}
+{{- end }}
+{{ end }}`
@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"goftw/internal/config"
+	"goftw/internal/secrets"
+)
+
+// Message is what the watcher reports, serialized as the webhook body
+type Message struct {
+	Host    string    `json:"host"`
+	Time    time.Time `json:"time"`
+	Subject string    `json:"subject"`
+	Events  []string  `json:"events"`
+}
+
+// New builds a message stamped with the current host and time
+func New(subject string, events []string) Message {
+	host, _ := os.Hostname()
+	return Message{Host: host, Time: time.Now().UTC(), Subject: subject, Events: events}
+}
+
+// Send delivers a message to every configured channel and returns the first failure
+// after trying all of them.
+func Send(cfg *config.WatchConfig, msg Message) error {
+	var firstErr error
+	if cfg.WebhookURL != "" {
+		if err := sendWebhook(cfg.WebhookURL, msg); err != nil {
+			fmt.Printf("[ERROR] Webhook notification failed: %v\n", err)
+			firstErr = err
+		}
+	}
+	if cfg.SMTP != nil {
+		if err := sendMail(cfg.SMTP, msg); err != nil {
+			fmt.Printf("[ERROR] Mail notification failed: %v\n", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+func sendWebhook(url string, msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+func sendMail(cfg *config.SMTPConfig, msg Message) error {
+	if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+		return fmt.Errorf("smtp needs host, from and to")
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = 25
+	}
+	addr := cfg.Host + ":" + strconv.Itoa(port)
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		password, err := secrets.Resolve(cfg.Password)
+		if err != nil {
+			return fmt.Errorf("failed to resolve smtp password: %v", err)
+		}
+		auth = smtp.PlainAuth("", cfg.Username, password, cfg.Host)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&b, "Subject: [goftw %s] %s\r\n", msg.Host, msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", msg.Time.Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	for _, e := range msg.Events {
+		fmt.Fprintf(&b, "- %s\r\n", e)
+	}
+	return smtp.SendMail(addr, auth, cfg.From, cfg.To, []byte(b.String()))
+}
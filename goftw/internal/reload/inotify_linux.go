@@ -0,0 +1,88 @@
+//go:build linux
+
+package reload
+
+import (
+	"fmt"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	dirEvents  = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_CREATE
+	fileEvents = syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY | syscall.IN_ATTRIB | syscall.IN_DELETE_SELF
+)
+
+// watchFiles reports the path of a file whenever inotify sees it written or replaced.
+// Each file is watched directly (bind-mounted files only emit events on themselves)
+// and through its directory (editors that save by renaming replace the inode).
+func watchFiles(paths []string, changed chan<- string) error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return fmt.Errorf("inotify init failed: %v", err)
+	}
+
+	byWatch := map[int32]string{} // file watches -> path
+	dirs := map[int32][]string{}  // directory watches -> paths inside
+	addFile := func(path string) {
+		if wd, err := syscall.InotifyAddWatch(fd, path, fileEvents); err == nil {
+			byWatch[int32(wd)] = path
+		}
+	}
+	for _, path := range paths {
+		addFile(path)
+		wd, err := syscall.InotifyAddWatch(fd, filepath.Dir(path), dirEvents)
+		if err != nil {
+			fmt.Printf("[WARN] Cannot watch directory of %s: %v\n", path, err)
+			continue
+		}
+		dirs[int32(wd)] = append(dirs[int32(wd)], path)
+	}
+
+	go func() {
+		defer syscall.Close(fd)
+		buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+		for {
+			n, err := syscall.Read(fd, buf)
+			if err != nil {
+				if err == syscall.EINTR {
+					continue
+				}
+				fmt.Printf("[ERROR] inotify read failed, hot reload stops: %v\n", err)
+				return
+			}
+			for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+				event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+				nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(event.Len)]
+				offset += syscall.SizeofInotifyEvent + int(event.Len)
+
+				if path, ok := byWatch[event.Wd]; ok {
+					if event.Mask&(syscall.IN_IGNORED|syscall.IN_DELETE_SELF) != 0 {
+						delete(byWatch, event.Wd)
+					}
+					changed <- path
+					continue
+				}
+				name := string(trimNul(nameBytes))
+				for _, path := range dirs[event.Wd] {
+					if filepath.Base(path) == name {
+						// The file may be a new inode now, watch it again
+						addFile(path)
+						changed <- path
+					}
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func trimNul(b []byte) []byte {
+	for i, c := range b {
+		if c == 0 {
+			return b[:i]
+		}
+	}
+	return b
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package reload
+
+// watchFiles has no inotify outside linux; the periodic check in Loop picks up changes.
+func watchFiles(paths []string, changed chan<- string) error {
+	return nil
+}
@@ -0,0 +1,268 @@
+package reload
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+
+	"goftw/internal/backup"
+	"goftw/internal/bench"
+	"goftw/internal/certs"
+	"goftw/internal/config"
+	"goftw/internal/db"
+	"goftw/internal/environ"
+	"goftw/internal/sites"
+	"goftw/internal/state"
+	"goftw/internal/supervisor"
+	"goftw/internal/updates"
+)
+
+const (
+	// debounce waits for editors to finish writing before a file is read
+	debounce = 2 * time.Second
+	// pollInterval re-reads the files in case an event was missed
+	pollInterval = 30 * time.Second
+	// lastGoodState keeps the last instance.json that passed validation; worker
+	// processes read it instead of the watched file so they see the applied version
+	lastGoodState = "instance.last-good.json"
+)
+
+// Loop watches instance.json and common_site_config.json and applies valid changes
+// without restarting the web processes. An invalid instance.json is rejected and the
+// last good one stays in effect. Applied configs are published to live. It never returns
+// and is meant to run in a goroutine.
+func Loop(live *config.Live, benchDir string, dbCfg db.Config) {
+	instancePath := environ.GetInstanceFile()
+	commonPath := environ.GetCommonSitesConfigPath()
+
+	lastInstance, _ := os.ReadFile(instancePath)
+	lastCommon, _ := os.ReadFile(commonPath)
+	if err := pinInstance(lastInstance, live.Instance()); err != nil {
+		fmt.Printf("[ERROR] Failed to record the applied instance.json: %v\n", err)
+	}
+
+	changed := make(chan string, 16)
+	if err := watchFiles([]string{instancePath, commonPath}, changed); err != nil {
+		fmt.Printf("[WARN] Watching config files failed, polling every %s instead: %v\n", pollInterval, err)
+	}
+	fmt.Printf("[RELOAD] Watching %s and %s for changes\n", instancePath, commonPath)
+
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+	for {
+		select {
+		case <-changed:
+		case <-poll.C:
+		}
+		// Let the writer finish, then swallow the burst of events it caused
+		settle(changed)
+
+		if data, err := os.ReadFile(instancePath); err == nil && !bytes.Equal(data, lastInstance) {
+			// Rejected content is not retried until it changes again
+			applyInstance(live, data, benchDir, dbCfg)
+			lastInstance = data
+		}
+		if data, err := os.ReadFile(commonPath); err == nil && !bytes.Equal(data, lastCommon) {
+			applyCommon(live, commonPath, benchDir)
+			lastCommon = data
+		}
+	}
+}
+
+// settle waits until no change was reported for the debounce period
+func settle(changed <-chan string) {
+	timer := time.NewTimer(debounce)
+	defer timer.Stop()
+	for {
+		select {
+		case <-changed:
+			timer.Reset(debounce)
+		case <-timer.C:
+			return
+		}
+	}
+}
+
+// pinInstance saves the applied instance.json and points worker processes at it. Relative
+// local app paths keep resolving against the directory of the user's instance.json.
+func pinInstance(data []byte, applied *config.InstanceConfig) error {
+	pinned, err := pinnedInstance(data, applied)
+	if err != nil {
+		return err
+	}
+	if err := state.Save(lastGoodState, pinned); err != nil {
+		return err
+	}
+	if err := os.Setenv(environ.InstanceDirEnv, environ.GetInstanceDir()); err != nil {
+		return err
+	}
+	return os.Setenv("INSTANCE_JSON_SOURCE", state.Path(lastGoodState))
+}
+
+// pinnedInstance returns instance.json as it is applied: settings that only take effect on
+// a restart keep their running values, so workers do not act on them early. Everything else
+// stays as written, local app paths included.
+func pinnedInstance(data []byte, applied *config.InstanceConfig) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for key, value := range map[string]string{"deployment": applied.Deployment, "frappe_branch": applied.FrappeBranch} {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		fields[key] = encoded
+	}
+	return json.Marshal(fields)
+}
+
+// applyInstance validates a new instance.json and reconciles the sites it changes
+func applyInstance(live *config.Live, data []byte, benchDir string, dbCfg db.Config) {
+	prev := live.Instance()
+	next, err := config.ParseInstance(data)
+	if err == nil {
+		err = next.Validate()
+	}
+	if err != nil {
+		fmt.Printf("[ERROR] Rejected instance.json change, keeping the last good config: %v\n", err)
+		return
+	}
+	if next.Workers != prev.Workers {
+		fmt.Println("[WARN] workers changes take effect on the next restart")
+	}
+	if next.Deployment != prev.Deployment || next.FrappeBranch != prev.FrappeBranch {
+		fmt.Println("[WARN] deployment and frappe_branch changes take effect on the next restart")
+		next.Deployment, next.FrappeBranch = prev.Deployment, prev.FrappeBranch
+	}
+
+	changedSites, removedSites := diffSites(prev, next)
+	fmt.Printf("[RELOAD] instance.json changed: %d site(s) to reconcile, %d removed\n", len(changedSites), len(removedSites))
+	if err := pinInstance(data, next); err != nil {
+		fmt.Printf("[ERROR] Failed to record the applied instance.json: %v\n", err)
+		return
+	}
+	// Do not interleave with the drift watcher reconciling at the same time
+	unlock, err := state.Lock("reconcile")
+	if err != nil {
+		fmt.Printf("[ERROR] Failed to lock reconciliation: %v\n", err)
+		return
+	}
+	defer unlock()
+	live.SetInstance(next)
+	instanceCfg := next
+	backup.SetSafetyPolicy(instanceCfg.SafetyBackups)
+	bench.SetMirrors(instanceCfg.Mirrors)
+	bench.SetLocalApps(instanceCfg.LocalApps)
+	bench.SetPinnedRefs(updates.PinnedRefs(instanceCfg.Updates))
+
+	sites.ResetResults()
+	if err := sites.RenameSites(instanceCfg, benchDir); err != nil {
+		fmt.Printf("[ERROR] %v\n", err)
+	}
+	if len(removedSites) > 0 {
+		current, err := bench.ListSites(benchDir)
+		if err == nil {
+			err = sites.DropAbandonedSites(instanceCfg, current, dbCfg.Password)
+		}
+		if err != nil {
+			fmt.Printf("[ERROR] Failed to handle removed sites: %v\n", err)
+		}
+	}
+	if len(changedSites) > 0 {
+		// A site archived as abandoned may be listed again
+		if err := sites.ReviveListedSites(instanceCfg); err != nil {
+			fmt.Printf("[ERROR] Failed to revive abandoned sites listed again: %v\n", err)
+		}
+		if err := sites.CheckoutSiteNames(instanceCfg, changedSites); err != nil {
+			fmt.Printf("[ERROR] %v\n", err)
+		}
+	}
+	sites.Summarize()
+
+	if instanceCfg.Deployment == "production" && nginxChanged(prev, next) {
+		if instanceCfg.TLS != nil {
+			if err := sites.CheckoutTLS(instanceCfg); err != nil {
+				fmt.Printf("[ERROR] TLS provisioning failed: %v\n", err)
+			}
+		}
+		// Regenerating the config reloads nginx; gunicorn and workers keep running
+		if err := supervisor.SetupNginx(instanceCfg, benchDir); err != nil {
+			fmt.Printf("[ERROR] Nginx setup failed: %v\n", err)
+		}
+	}
+}
+
+// applyCommon copies a changed common_site_config.json into the bench
+func applyCommon(live *config.Live, path, benchDir string) {
+	instanceCfg, commonCfg := live.Instance(), live.Common()
+	next, err := config.LoadCommonSitesConfig(path)
+	if err != nil {
+		fmt.Printf("[ERROR] Rejected common_site_config.json change: %v\n", err)
+		return
+	}
+	if next.RedisQueue != commonCfg.RedisQueue || next.RedisCache != commonCfg.RedisCache || next.RedisSocketIO != commonCfg.RedisSocketIO {
+		fmt.Println("[WARN] Redis changes in common_site_config.json take effect on the next restart")
+	}
+	if err := bench.CopyCommonSitesConfig(benchDir, path); err != nil {
+		fmt.Printf("[ERROR] Failed to copy common_site_config.json into the bench: %v\n", err)
+		return
+	}
+	live.SetCommon(next)
+	fmt.Println("[RELOAD] common_site_config.json applied")
+	if instanceCfg.Deployment == "production" && nginxCommonChanged(commonCfg, next) {
+		if err := supervisor.SetupNginx(instanceCfg, benchDir); err != nil {
+			fmt.Printf("[ERROR] Nginx setup failed: %v\n", err)
+		}
+	}
+}
+
+// nginxSite holds the parts of a site the generated nginx config is rendered from
+type nginxSite struct {
+	ServerNames []string
+	Nginx       config.NginxSiteConfig
+}
+
+// nginxChanged reports whether the nginx config or the certificates it serves depend on
+// anything that differs between the two instance configs.
+func nginxChanged(prev, next *config.InstanceConfig) bool {
+	rendered := func(cfg *config.InstanceConfig) map[string]nginxSite {
+		out := map[string]nginxSite{}
+		for _, site := range cfg.InstanceSites {
+			out[site.SiteName] = nginxSite{certs.SiteNames(site), site.Nginx}
+		}
+		return out
+	}
+	return !reflect.DeepEqual(prev.TLS, next.TLS) || !reflect.DeepEqual(rendered(prev), rendered(next))
+}
+
+// nginxCommonChanged reports whether a common_site_config.json change touches the ports
+// or timeout the nginx config is rendered with.
+func nginxCommonChanged(prev, next *config.CommonConfig) bool {
+	return prev.WebserverPort != next.WebserverPort || prev.SocketIOPort != next.SocketIOPort || prev.HTTPTimeout != next.HTTPTimeout
+}
+
+// diffSites returns the sites that are new or whose settings changed, and the sites
+// that are no longer listed.
+func diffSites(prev, next *config.InstanceConfig) (changed, removed []string) {
+	before := map[string]config.InstanceSite{}
+	for _, site := range prev.InstanceSites {
+		before[site.SiteName] = site
+	}
+	for _, site := range next.InstanceSites {
+		old, ok := before[site.SiteName]
+		if !ok || !reflect.DeepEqual(old, site) {
+			changed = append(changed, site.SiteName)
+		}
+		delete(before, site.SiteName)
+	}
+	for _, site := range prev.InstanceSites {
+		if _, ok := before[site.SiteName]; ok {
+			removed = append(removed, site.SiteName)
+		}
+	}
+	return changed, removed
+}
@@ -0,0 +1,116 @@
+package reload
+
+import (
+	"reflect"
+	"testing"
+
+	"goftw/internal/config"
+)
+
+func TestDiffSites(t *testing.T) {
+	sites := func(list ...config.InstanceSite) *config.InstanceConfig {
+		return &config.InstanceConfig{InstanceSites: list}
+	}
+	a := config.InstanceSite{SiteName: "a.local", Apps: []string{"frappe"}}
+	b := config.InstanceSite{SiteName: "b.local", Apps: []string{"frappe", "erpnext"}}
+	c := config.InstanceSite{SiteName: "c.local"}
+	bMoreApps := config.InstanceSite{SiteName: "b.local", Apps: []string{"frappe", "erpnext", "hrms"}}
+	aWithConfig := config.InstanceSite{SiteName: "a.local", Apps: []string{"frappe"}, SiteConfig: map[string]any{"mute_emails": 1}}
+
+	tests := []struct {
+		name          string
+		prev, next    *config.InstanceConfig
+		changed, gone []string
+	}{
+		{"unchanged", sites(a, b), sites(a, b), nil, nil},
+		{"reordered", sites(a, b), sites(b, a), nil, nil},
+		{"site added", sites(a), sites(a, c), []string{"c.local"}, nil},
+		{"site removed", sites(a, b), sites(b), nil, []string{"a.local"}},
+		{"apps changed", sites(a, b), sites(a, bMoreApps), []string{"b.local"}, nil},
+		{"site_config changed", sites(a, b), sites(aWithConfig, b), []string{"a.local"}, nil},
+		{"everything at once", sites(a, b), sites(aWithConfig, c), []string{"a.local", "c.local"}, []string{"b.local"}},
+		{"from empty", sites(), sites(a, b), []string{"a.local", "b.local"}, nil},
+		{"to empty", sites(a, b), sites(), nil, []string{"a.local", "b.local"}},
+	}
+	for _, tt := range tests {
+		changed, removed := diffSites(tt.prev, tt.next)
+		if !reflect.DeepEqual(changed, tt.changed) {
+			t.Errorf("%s: changed = %v, want %v", tt.name, changed, tt.changed)
+		}
+		if !reflect.DeepEqual(removed, tt.gone) {
+			t.Errorf("%s: removed = %v, want %v", tt.name, removed, tt.gone)
+		}
+	}
+}
+
+func TestPinnedInstance(t *testing.T) {
+	data := []byte(`{"deployment": "production", "frappe_branch": "version-16",
+		"instance_sites": [{"site_name": "a.local", "apps": ["frappe", "./apps/my_app"]}]}`)
+	applied := &config.InstanceConfig{Deployment: "develop", FrappeBranch: "version-15"}
+
+	pinned, err := pinnedInstance(data, applied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.ParseInstance(pinned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Deployment != "develop" || cfg.FrappeBranch != "version-15" {
+		t.Errorf("pinned deployment %q, frappe_branch %q, want the running develop and version-15", cfg.Deployment, cfg.FrappeBranch)
+	}
+	if want := map[string]string{"my_app": "./apps/my_app"}; !reflect.DeepEqual(cfg.LocalApps, want) {
+		t.Errorf("pinned local apps = %v, want %v", cfg.LocalApps, want)
+	}
+	if len(cfg.InstanceSites) != 1 || !reflect.DeepEqual(cfg.InstanceSites[0].Apps, []string{"frappe", "my_app"}) {
+		t.Errorf("pinned sites = %+v", cfg.InstanceSites)
+	}
+
+	if _, err := pinnedInstance([]byte(`[]`), applied); err == nil {
+		t.Errorf("pinnedInstance accepted a non-object instance.json")
+	}
+}
+
+func TestNginxChanged(t *testing.T) {
+	sites := func(list ...config.InstanceSite) *config.InstanceConfig {
+		return &config.InstanceConfig{InstanceSites: list}
+	}
+	a := config.InstanceSite{SiteName: "a.local", Apps: []string{"frappe"}}
+	b := config.InstanceSite{SiteName: "b.local"}
+	aMoreApps := config.InstanceSite{SiteName: "a.local", Apps: []string{"frappe", "erpnext"}, SiteConfig: map[string]any{"mute_emails": 1}}
+	aDomain := config.InstanceSite{SiteName: "a.local", Apps: []string{"frappe"}, Domains: []string{"a.example.com"}}
+	aBodySize := config.InstanceSite{SiteName: "a.local", Apps: []string{"frappe"}, Nginx: config.NginxSiteConfig{ClientMaxBodySize: "100m"}}
+	withTLS := sites(a, b)
+	withTLS.TLS = &config.TLSConfig{Mode: "self_signed"}
+
+	tests := []struct {
+		name       string
+		prev, next *config.InstanceConfig
+		want       bool
+	}{
+		{"unchanged", sites(a, b), sites(a, b), false},
+		{"reordered", sites(a, b), sites(b, a), false},
+		{"apps and site_config changed", sites(a, b), sites(aMoreApps, b), false},
+		{"site added", sites(a), sites(a, b), true},
+		{"site removed", sites(a, b), sites(a), true},
+		{"domain added", sites(a, b), sites(aDomain, b), true},
+		{"nginx settings changed", sites(a, b), sites(aBodySize, b), true},
+		{"tls enabled", sites(a, b), withTLS, true},
+	}
+	for _, tt := range tests {
+		if got := nginxChanged(tt.prev, tt.next); got != tt.want {
+			t.Errorf("%s: nginxChanged = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	common := config.CommonConfig{WebserverPort: 8000, SocketIOPort: 9000, HTTPTimeout: 120}
+	redis, port := common, common
+	redis.RedisCache = "redis://cache:6379"
+	port.WebserverPort = 8001
+	if nginxCommonChanged(&common, &redis) {
+		t.Errorf("a redis change re-renders nginx")
+	}
+	if !nginxCommonChanged(&common, &port) {
+		t.Errorf("a webserver_port change does not re-render nginx")
+	}
+}
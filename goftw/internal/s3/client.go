@@ -0,0 +1,297 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PartSize is the size of each part of a multipart upload; smaller files use a single PUT.
+const PartSize = 16 << 20
+
+// Client is a minimal S3-compatible object storage client (AWS S3, MinIO, ...)
+type Client struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	pathStyle bool
+	http      *http.Client
+}
+
+// Object is an entry returned by List
+type Object struct {
+	Key          string    `xml:"Key"`
+	Size         int64     `xml:"Size"`
+	LastModified time.Time `xml:"LastModified"`
+}
+
+// New returns a client for the bucket at endpoint (e.g. http://minio:9000).
+// Path-style addressing is required by MinIO and most self-hosted stores.
+func New(endpoint, region, bucket, accessKey, secretKey string, pathStyle bool) (*Client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid object storage endpoint %q", endpoint)
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &Client{
+		endpoint:  u,
+		region:    region,
+		bucket:    bucket,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		pathStyle: pathStyle,
+		http:      &http.Client{Timeout: 30 * time.Minute},
+	}, nil
+}
+
+// Upload stores a local file under key, using multipart upload for large files.
+// Every request carries a SHA-256 checksum that the server verifies before accepting the data;
+// sha256Hex is the checksum the file is expected to have, e.g. from the backup catalog.
+func (c *Client) Upload(key, path, sha256Hex string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if info.Size() <= PartSize {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		sum, err := hex.DecodeString(sha256Hex)
+		if err != nil {
+			return fmt.Errorf("invalid checksum for %s: %v", path, err)
+		}
+		// The server rejects the upload if the data does not match the expected checksum
+		headers := map[string]string{"x-amz-checksum-sha256": base64.StdEncoding.EncodeToString(sum)}
+		resp, err := c.do("PUT", key, nil, data, headers)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+	return c.uploadMultipart(key, f, sha256Hex)
+}
+
+type completedPart struct {
+	PartNumber     int    `xml:"PartNumber"`
+	ETag           string `xml:"ETag"`
+	ChecksumSHA256 string `xml:"ChecksumSHA256"`
+}
+
+func (c *Client) uploadMultipart(key string, r io.Reader, sha256Hex string) error {
+	resp, err := c.do("POST", key, url.Values{"uploads": {""}}, nil, map[string]string{"x-amz-checksum-algorithm": "SHA256"})
+	if err != nil {
+		return err
+	}
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	err = xml.NewDecoder(resp.Body).Decode(&initiated)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to parse multipart upload response: %v", err)
+	}
+
+	var parts []completedPart
+	whole := sha256.New()
+	buf := make([]byte, PartSize)
+	for number := 1; ; number++ {
+		n, readErr := io.ReadFull(io.TeeReader(r, whole), buf)
+		if n == 0 {
+			break
+		}
+		data := buf[:n]
+		headers := checksumHeader(data)
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {initiated.UploadID}}
+		resp, err := c.do("PUT", key, query, data, headers)
+		if err != nil {
+			c.abort(key, initiated.UploadID)
+			return fmt.Errorf("part %d: %v", number, err)
+		}
+		resp.Body.Close()
+		parts = append(parts, completedPart{
+			PartNumber:     number,
+			ETag:           resp.Header.Get("ETag"),
+			ChecksumSHA256: headers["x-amz-checksum-sha256"],
+		})
+		if readErr != nil {
+			break // io.EOF or io.ErrUnexpectedEOF: last part
+		}
+	}
+
+	// Parts are verified individually by the server; make sure together they are the expected file
+	if actual := hex.EncodeToString(whole.Sum(nil)); actual != sha256Hex {
+		c.abort(key, initiated.UploadID)
+		return fmt.Errorf("checksum mismatch for %s: expected %s, read %s", key, sha256Hex, actual)
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+	resp, err = c.do("POST", key, url.Values{"uploadId": {initiated.UploadID}}, body, nil)
+	if err != nil {
+		c.abort(key, initiated.UploadID)
+		return err
+	}
+	defer resp.Body.Close()
+	// S3 may report a failed completion with a 200 status and an error document
+	respBody, _ := io.ReadAll(resp.Body)
+	if bytes.Contains(respBody, []byte("<Error>")) {
+		c.abort(key, initiated.UploadID)
+		return fmt.Errorf("complete multipart upload failed: %s", respBody)
+	}
+	return nil
+}
+
+func (c *Client) abort(key, uploadID string) {
+	if resp, err := c.do("DELETE", key, url.Values{"uploadId": {uploadID}}, nil, nil); err == nil {
+		resp.Body.Close()
+	}
+}
+
+// List returns all objects whose key starts with prefix
+func (c *Client) List(prefix string) ([]Object, error) {
+	var objects []Object
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := c.do("GET", "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Contents              []Object `xml:"Contents"`
+			IsTruncated           bool     `xml:"IsTruncated"`
+			NextContinuationToken string   `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse object listing: %v", err)
+		}
+		objects = append(objects, page.Contents...)
+		if !page.IsTruncated {
+			return objects, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// Download writes the object stored under key to path.
+// sha256Hex is the checksum the object is expected to have, e.g. from the backup manifest;
+// the object only replaces path once it matches. An empty sha256Hex skips the check.
+func (c *Client) Download(key, path, sha256Hex string) error {
+	resp, err := c.do("GET", key, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), resp.Body); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if actual := hex.EncodeToString(h.Sum(nil)); sha256Hex != "" && actual != sha256Hex {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, downloaded %s", key, sha256Hex, actual)
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Delete removes the object stored under key
+func (c *Client) Delete(key string) error {
+	resp, err := c.do("DELETE", key, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do signs and sends a request, turning non-2xx responses into errors
+func (c *Client) do(method, key string, query url.Values, body []byte, headers map[string]string) (*http.Response, error) {
+	u := *c.endpoint
+	path := "/" + strings.TrimPrefix(key, "/")
+	if c.pathStyle {
+		path = "/" + c.bucket + path
+	} else {
+		u.Host = c.bucket + "." + u.Host
+	}
+	if key == "" && !c.pathStyle {
+		path = "/"
+	}
+	u.Path = path
+	u.RawPath = encodePath(path)
+	if query != nil {
+		u.RawQuery = canonicalQuery(query)
+	}
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	payloadHash := emptySHA256
+	if len(body) > 0 {
+		payloadHash = hexSHA256(body)
+	}
+	c.sign(req, payloadHash, time.Now())
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s: %s: %s", method, key, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+// checksumHeader returns the header asking the server to verify the body's SHA-256
+func checksumHeader(data []byte) map[string]string {
+	sum := sha256.Sum256(data)
+	return map[string]string{
+		"x-amz-checksum-sha256": base64.StdEncoding.EncodeToString(sum[:]),
+	}
+}
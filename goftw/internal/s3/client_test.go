@@ -0,0 +1,45 @@
+package s3
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownloadVerifiesChecksum(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Welcome to Amazon S3."))
+	}))
+	defer srv.Close()
+	c, err := New(srv.URL, "", "bucket", "key", "secret", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	good := "44ce7dd67c959e0d3524ffac1771dfbba87d2b6b4b4e99e42034a8b803f8b072"
+
+	tests := []struct {
+		name    string
+		sha256  string
+		wantErr bool
+	}{
+		{"matching checksum", good, false},
+		{"no checksum", "", false},
+		{"corrupt object", emptySHA256, true},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "db.sql.gz")
+		err := c.Download("site/db.sql.gz", path, tt.sha256)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Download error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		_, statErr := os.Stat(path)
+		if tt.wantErr != os.IsNotExist(statErr) {
+			t.Errorf("%s: file left at %s = %v", tt.name, path, statErr == nil)
+		}
+		if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) > 1 {
+			t.Errorf("%s: temporary files left behind: %d entries", tt.name, len(entries))
+		}
+	}
+}
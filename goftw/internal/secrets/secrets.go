@@ -0,0 +1,141 @@
+package secrets
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"goftw/internal/environ"
+	"goftw/internal/state"
+)
+
+const passwordAlphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789-_.!@#%"
+
+// Load reads the local secrets file into a map, returning an empty map if it does not exist.
+func Load() (map[string]string, error) {
+	store := map[string]string{}
+	data, err := os.ReadFile(environ.GetSecretsFile())
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &store); err != nil {
+		return nil, fmt.Errorf("failed to parse secrets file: %v", err)
+	}
+	return store, nil
+}
+
+// Save writes the secrets map to the local secrets file, readable only by the owner.
+func Save(store map[string]string) error {
+	path := environ.GetSecretsFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create secrets directory: %v", err)
+	}
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return err
+	}
+	// CreateTemp makes the file 0600 and unique, so concurrent writers never share it
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write secrets file: %v", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write secrets file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Update applies change to the secrets under a lock shared by all goftw processes, so
+// parallel workers never overwrite each other's secrets.
+func Update(change func(store map[string]string) error) error {
+	unlock, err := state.Lock("secrets")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	store, err := Load()
+	if err != nil {
+		return err
+	}
+	if err := change(store); err != nil {
+		return err
+	}
+	return Save(store)
+}
+
+// Get returns a stored secret and whether it was present.
+func Get(key string) (string, bool, error) {
+	store, err := Load()
+	if err != nil {
+		return "", false, err
+	}
+	val, ok := store[key]
+	return val, ok, nil
+}
+
+// Set stores a secret, replacing any previous value.
+func Set(key, value string) error {
+	return Update(func(store map[string]string) error {
+		store[key] = value
+		return nil
+	})
+}
+
+// Resolve resolves a secret reference of the form env:NAME, file:/path or secret:key.
+func Resolve(ref string) (string, error) {
+	kind, target, ok := strings.Cut(ref, ":")
+	if !ok {
+		return "", fmt.Errorf("invalid secret reference %q, expected env:, file: or secret:", ref)
+	}
+	switch kind {
+	case "env":
+		val := os.Getenv(target)
+		if val == "" {
+			return "", fmt.Errorf("environment variable %s is empty", target)
+		}
+		return val, nil
+	case "file":
+		data, err := os.ReadFile(target)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file %s: %v", target, err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	case "secret":
+		val, ok, err := Get(target)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "", fmt.Errorf("secret %s not found in %s", target, environ.GetSecretsFile())
+		}
+		return val, nil
+	}
+	return "", fmt.Errorf("unknown secret reference kind %q", kind)
+}
+
+// GeneratePassword returns a random password of the given length.
+func GeneratePassword(length int) (string, error) {
+	max := big.NewInt(int64(len(passwordAlphabet)))
+	buf := make([]byte, length)
+	for i := range buf {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		buf[i] = passwordAlphabet[n.Int64()]
+	}
+	return string(buf), nil
+}
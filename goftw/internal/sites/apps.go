@@ -2,6 +2,7 @@ package sites
 
 import (
 	"fmt"
+	"goftw/internal/backup"
 	"goftw/internal/bench"
 	"goftw/internal/config"
 	"goftw/internal/utils"
@@ -13,7 +14,7 @@ import (
 // CheckoutApps makes sure all apps for a given site are aligned.
 func CheckoutApps(site config.InstanceSite, benchDir string) error {
 	// Ensure apps exist locally in bench/apps
-	if err := fetchMissingApps(site, benchDir); err != nil {
+	if err := fetchMissingApps(site.Apps, benchDir); err != nil {
 		fmt.Printf("[ERROR] Failed to fetch missing apps for site %s: %v\n", site.SiteName, err)
 		return err
 	}
@@ -44,14 +45,18 @@ func CheckoutApps(site config.InstanceSite, benchDir string) error {
 	return nil
 }
 
-// fetchMissingApps ensures that every app in instance.json exists in bench/apps
-func fetchMissingApps(site config.InstanceSite, benchDir string) error {
-	for _, app := range site.Apps {
+// fetchMissingApps ensures that every given app exists in bench/apps
+func fetchMissingApps(apps []string, benchDir string) error {
+	for _, app := range apps {
 		if app == "frappe" {
 			continue
 		}
 		appPath := filepath.Join(benchDir, "apps", app)
-		if _, err := os.Stat(appPath); os.IsNotExist(err) {
+		_, err := os.Stat(appPath)
+		if _, local := bench.LocalAppRef(app); local && err == nil && !bench.IsLocalApp(benchDir, app) {
+			fmt.Printf("[WARN] App %s is declared as a local app but the bench has its own checkout; remove apps/%s to link it\n", app, app)
+		}
+		if os.IsNotExist(err) {
 			fmt.Printf("[APP] Fetching missing app: %s\n", app)
 			if err := bench.GetApp(app, "develop"); err != nil {
 				fmt.Printf("[ERROR] Failed to fetch app %s: %v\n", app, err)
@@ -71,6 +76,8 @@ func installMissingApps(siteName string, expected, current []string) error {
 				fmt.Printf("[ERROR] Failed to install app %s on site %s: %v\n", app, siteName, err)
 				return err
 			}
+			recordResult(siteName, func(r *SiteResult) { r.AppsInstalled = append(r.AppsInstalled, app) })
+			recordMigratedCommits(siteName, app)
 		}
 	}
 	return nil
@@ -78,12 +85,21 @@ func installMissingApps(siteName string, expected, current []string) error {
 
 // uninstallExtraApps uninstalls apps that are present but not expected
 func uninstallExtraApps(siteName string, current, expected []string) error {
+	backedUp := false
 	for _, app := range utils.Difference(current, expected) {
 		if app != "frappe" {
+			if !backedUp {
+				if _, err := backup.Safety(siteName, "uninstall", false); err != nil {
+					fmt.Printf("[ERROR] Not uninstalling apps from site %s: %v\n", siteName, err)
+					return err
+				}
+				backedUp = true
+			}
 			fmt.Printf("[APPS] Uninstalling extra app: %s\n", app)
 			if err := UninstallApp(siteName, app); err != nil {
 				return err
 			}
+			recordResult(siteName, func(r *SiteResult) { r.AppsRemoved = append(r.AppsRemoved, app) })
 		}
 	}
 	return nil
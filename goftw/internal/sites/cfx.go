@@ -2,6 +2,16 @@ package sites
 
 import "goftw/internal/config"
 
+// siteByName returns the declaration of a site in the instance configuration
+func siteByName(cfg *config.InstanceConfig, name string) (config.InstanceSite, bool) {
+	for _, s := range cfg.InstanceSites {
+		if s.SiteName == name {
+			return s, true
+		}
+	}
+	return config.InstanceSite{}, false
+}
+
 // siteExistsInCfx checks if a site exists in the instance configuration
 func siteExistsInCfx(site string, cfg *config.InstanceConfig) bool {
 	for _, s := range cfg.InstanceSites {
@@ -0,0 +1,77 @@
+package sites
+
+import (
+	"fmt"
+
+	"goftw/internal/backup"
+	"goftw/internal/config"
+)
+
+// Clone creates a missing site as a copy of its clone_from site: the source is backed up
+// with its files and the set is restored under the new name. The caller then aligns the
+// copy's apps and site_config with its own declaration.
+func Clone(site config.InstanceSite, benchDir, dbRootUser, dbRootPass string) error {
+	source := site.CloneFrom
+	if !siteDirExists(benchDir, source) {
+		return fmt.Errorf("clone source %s does not exist", source)
+	}
+	fmt.Printf("[SITES] Cloning site %s from %s\n", site.SiteName, source)
+
+	entry, err := backup.Run(source, true, "clone")
+	if err != nil {
+		fmt.Printf("[ERROR] Failed to back up clone source %s: %v\n", source, err)
+		return err
+	}
+	// Apps the copy does not declare are uninstalled right after, but must exist to restore
+	if err := fetchMissingApps(entry.Apps, benchDir); err != nil {
+		fmt.Printf("[ERROR] Failed to fetch apps of clone source %s: %v\n", source, err)
+		return err
+	}
+	if err := restoreSet(site.SiteName, entry.Dir, dbRootUser, dbRootPass); err != nil {
+		return err
+	}
+	recordResult(site.SiteName, func(r *SiteResult) { r.ClonedFrom = source })
+
+	// The copy carries the source's enabled scheduler: pause it and apply the copy's own
+	// site_config (e.g. disable_outgoing_emails) before anything else can run a job or
+	// send mail from it. CheckoutSite resumes the scheduler once the copy is reconciled.
+	if err := pauseScheduler(benchDir, site.SiteName); err != nil {
+		fmt.Printf("[ERROR] Failed to pause the scheduler of clone %s: %v\n", site.SiteName, err)
+		return err
+	}
+	if err := CheckoutSiteConfig(site, benchDir); err != nil {
+		fmt.Printf("[ERROR] Failed to apply site_config to clone %s: %v\n", site.SiteName, err)
+		return err
+	}
+	return nil
+}
+
+// pauseScheduler sets pause_scheduler in the site's site_config.json directly, which takes
+// effect on the scheduler's next tick without waiting for a bench command
+func pauseScheduler(benchDir, site string) error {
+	cfg, err := readSiteConfig(benchDir, site)
+	if err != nil {
+		return err
+	}
+	cfg["pause_scheduler"] = 1
+	return writeSiteConfig(benchDir, site, cfg)
+}
+
+// CloneSite clones src into dst on demand. When dst is declared in instance.json it is
+// reconciled like any other site; otherwise it is only copied.
+func CloneSite(instanceCfg *config.InstanceConfig, src, dst, benchDir, dbRootUser, dbRootPass string) error {
+	if siteDirExists(benchDir, dst) {
+		return fmt.Errorf("site %s already exists", dst)
+	}
+	for _, site := range instanceCfg.InstanceSites {
+		if site.SiteName == dst {
+			site.CloneFrom, site.RestoreFrom = src, ""
+			return CheckoutSite(instanceCfg, site, benchDir, dbRootUser, dbRootPass)
+		}
+	}
+	fmt.Printf("[WARN] Site %s is not listed in instance.json and may be treated as abandoned; its scheduler stays paused\n", dst)
+	if err := Clone(config.InstanceSite{SiteName: dst, CloneFrom: src}, benchDir, dbRootUser, dbRootPass); err != nil {
+		return err
+	}
+	return Migrate(dst)
+}
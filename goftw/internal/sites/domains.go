@@ -0,0 +1,64 @@
+package sites
+
+import (
+	"fmt"
+	"sort"
+
+	"goftw/internal/bench"
+	"goftw/internal/config"
+	"goftw/internal/utils"
+)
+
+// CheckoutDomains aligns the site's custom domains with instance.json.
+// It reports whether any domain was added or removed so nginx can be regenerated.
+func CheckoutDomains(site config.InstanceSite, benchDir string) (bool, error) {
+	current, err := ListDomains(site.SiteName, benchDir)
+	if err != nil {
+		fmt.Printf("[ERROR] Failed to read domains for site %s: %v\n", site.SiteName, err)
+		return false, err
+	}
+	expected := append([]string{}, site.Domains...)
+	sort.Strings(current)
+	sort.Strings(expected)
+
+	changed := false
+	for _, domain := range utils.Difference(expected, current) {
+		fmt.Printf("[DOMAINS] Adding domain %s to site %s\n", domain, site.SiteName)
+		if err := bench.RunInBenchPrintIO("setup", "add-domain", domain, "--site", site.SiteName); err != nil {
+			fmt.Printf("[ERROR] Failed to add domain %s to site %s: %v\n", domain, site.SiteName, err)
+			return changed, err
+		}
+		changed = true
+	}
+	for _, domain := range utils.Difference(current, expected) {
+		fmt.Printf("[DOMAINS] Removing stale domain %s from site %s\n", domain, site.SiteName)
+		if err := bench.RunInBenchPrintIO("setup", "remove-domain", domain, "--site", site.SiteName); err != nil {
+			fmt.Printf("[ERROR] Failed to remove domain %s from site %s: %v\n", domain, site.SiteName, err)
+			return changed, err
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+// ListDomains returns the custom domains recorded in the site's site_config.json.
+// Entries may be plain hostnames or objects carrying a "domain" key and certificate paths.
+func ListDomains(siteName, benchDir string) ([]string, error) {
+	cfg, err := readSiteConfig(benchDir, siteName)
+	if err != nil {
+		return nil, err
+	}
+	entries, _ := cfg["domains"].([]any)
+	domains := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		switch d := entry.(type) {
+		case string:
+			domains = append(domains, d)
+		case map[string]any:
+			if name, ok := d["domain"].(string); ok {
+				domains = append(domains, name)
+			}
+		}
+	}
+	return domains, nil
+}
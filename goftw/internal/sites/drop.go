@@ -2,24 +2,202 @@ package sites
 
 import (
 	"fmt"
+	"time"
+
+	"goftw/internal/backup"
 	"goftw/internal/bench"
 	"goftw/internal/config"
+	"goftw/internal/state"
 )
 
-// DropAbandonedSites drops sites that exist in the bench but are not listed in instance.json
+// abandonedState records the sites that were archived and await dropping
+const abandonedState = "abandoned_sites.json"
+
+// AbandonedSite is an unlisted site kept in maintenance mode until it is dropped
+type AbandonedSite struct {
+	Since     time.Time `json:"since"`
+	ArchiveID string    `json:"archive_id"`
+}
+
+// DropAbandonedSites handles sites that exist in the bench but are not listed in instance.json:
+// with the "drop" policy they are dropped at once, with "archive" they are archived, put in
+// maintenance mode and only dropped after the grace period. Protected sites are never touched.
 func DropAbandonedSites(cfg *config.InstanceConfig, currentSites []string, dbRootPass string) error {
+	// Sites archived earlier are revived once listed again, even if dropping was turned off since
+	if err := ReviveListedSites(cfg); err != nil {
+		fmt.Printf("[ERROR] Failed to revive abandoned sites listed again: %v\n", err)
+	}
 	if !cfg.DropAbandonedSites {
 		fmt.Println("[SITES] Skipping drop of abandoned sites")
 		return nil
 	}
 
 	for _, site := range currentSites {
-		if !siteExistsInCfx(site, cfg) {
-			fmt.Printf("[SITES] Dropping unlisted site: %s\n", site)
-			if err := bench.RunInBenchPrintIO("drop-site", site, "--force", "--root-password", dbRootPass); err != nil {
-				fmt.Printf("[ERROR] Failed to drop site %s: %v\n", site, err)
+		if siteExistsInCfx(site, cfg) {
+			continue
+		}
+		if IsProtected(cfg, site) {
+			fmt.Printf("[SITES] Keeping protected site %s although it is not listed\n", site)
+			continue
+		}
+		if isPreviousName(cfg, site) {
+			fmt.Printf("[WARN] Keeping site %s: it is a previous name of a listed site that already exists\n", site)
+			continue
+		}
+		if cfg.AbandonedSites.Policy == "archive" {
+			if err := abandonSite(cfg, site, dbRootPass); err != nil {
+				fmt.Printf("[ERROR] Failed to handle abandoned site %s: %v\n", site, err)
+				RecordError(site, "abandon", err)
 			}
+			continue
+		}
+
+		// Keep a full backup so the dropped site can be restored during the retention period
+		if _, err := backup.Safety(site, "drop", true); err != nil {
+			fmt.Printf("[ERROR] Not dropping site %s: %v\n", site, err)
+			RecordError(site, "drop", err)
+			continue
+		}
+		// Failures are recorded per site, the other sites are still handled
+		_ = dropSite(site, dbRootPass)
+	}
+	return nil
+}
+
+// abandonSite archives a newly abandoned site, or drops it once its grace period is over
+func abandonSite(cfg *config.InstanceConfig, site, dbRootPass string) error {
+	abandoned, err := loadAbandoned()
+	if err != nil {
+		return err
+	}
+
+	if rec, ok := abandoned[site]; ok {
+		grace := cfg.AbandonedSites.GracePeriodDays
+		if grace == 0 {
+			fmt.Printf("[SITES] Site %s abandoned since %s, waiting for `sites drop %s`\n", site, rec.Since.Local().Format("2006-01-02"), site)
+			return nil
+		}
+		dropAt := rec.Since.Add(time.Duration(grace) * 24 * time.Hour)
+		if time.Now().Before(dropAt) {
+			fmt.Printf("[SITES] Site %s abandoned, will be dropped after %s\n", site, dropAt.Local().Format("2006-01-02 15:04"))
+			return nil
 		}
+		fmt.Printf("[SITES] Grace period of abandoned site %s is over (archive %s)\n", site, rec.ArchiveID)
+		if err := dropSite(site, dbRootPass); err != nil {
+			return err
+		}
+		return forgetAbandoned(site)
+	}
+
+	if err := ShortHandRunOnSite(site, "set-maintenance-mode", "on"); err != nil {
+		return fmt.Errorf("failed to enable maintenance mode: %v", err)
+	}
+	entry, err := backup.Archive(site, cfg.ObjectStorage)
+	if err != nil {
+		return err
 	}
+	if err := updateAbandoned(func(all map[string]AbandonedSite) {
+		all[site] = AbandonedSite{Since: time.Now().UTC(), ArchiveID: entry.ID}
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("[SITES] Site %s is not listed: archived as %s and put in maintenance mode\n", site, entry.ID)
+	recordResult(site, func(r *SiteResult) { r.Archived = true })
 	return nil
 }
+
+// ConfirmDrop drops an abandoned site before its grace period is over
+func ConfirmDrop(cfg *config.InstanceConfig, site, dbRootPass string) error {
+	if siteExistsInCfx(site, cfg) {
+		return fmt.Errorf("site %s is listed in instance.json", site)
+	}
+	if IsProtected(cfg, site) {
+		return fmt.Errorf("site %s is protected", site)
+	}
+	abandoned, err := loadAbandoned()
+	if err != nil {
+		return err
+	}
+	rec, ok := abandoned[site]
+	if !ok {
+		return fmt.Errorf("site %s was not archived as abandoned", site)
+	}
+	fmt.Printf("[SITES] Dropping abandoned site %s on confirmation (archive %s)\n", site, rec.ArchiveID)
+	if err := dropSite(site, dbRootPass); err != nil {
+		return err
+	}
+	return forgetAbandoned(site)
+}
+
+// ReviveListedSites takes sites that are listed again out of the abandoned state
+func ReviveListedSites(cfg *config.InstanceConfig) error {
+	abandoned, err := loadAbandoned()
+	if err != nil {
+		return err
+	}
+	for site := range abandoned {
+		if !siteExistsInCfx(site, cfg) {
+			continue
+		}
+		fmt.Printf("[SITES] Site %s is listed again, leaving maintenance mode\n", site)
+		if err := ShortHandRunOnSite(site, "set-maintenance-mode", "off"); err != nil {
+			return err
+		}
+		if err := forgetAbandoned(site); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dropSite(site, dbRootPass string) error {
+	fmt.Printf("[SITES] Dropping unlisted site: %s\n", site)
+	if err := bench.RunInBenchPrintIO("drop-site", site, "--force", "--root-password", dbRootPass); err != nil {
+		fmt.Printf("[ERROR] Failed to drop site %s: %v\n", site, err)
+		RecordError(site, "drop", err)
+		return err
+	}
+	recordResult(site, func(r *SiteResult) { r.Dropped = true })
+	return nil
+}
+
+// IsProtected reports whether a site is listed in abandoned_sites.protected_sites
+func IsProtected(cfg *config.InstanceConfig, site string) bool {
+	for _, p := range cfg.AbandonedSites.ProtectedSites {
+		if p == site {
+			return true
+		}
+	}
+	return false
+}
+
+// Abandoned returns the sites waiting to be dropped under the archive policy
+func Abandoned() (map[string]AbandonedSite, error) {
+	return loadAbandoned()
+}
+
+func loadAbandoned() (map[string]AbandonedSite, error) {
+	all := map[string]AbandonedSite{}
+	if err := state.Load(abandonedState, &all); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+func updateAbandoned(update func(map[string]AbandonedSite)) error {
+	unlock, err := state.Lock(abandonedState)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	all, err := loadAbandoned()
+	if err != nil {
+		return err
+	}
+	update(all)
+	return state.Save(abandonedState, all)
+}
+
+func forgetAbandoned(site string) error {
+	return updateAbandoned(func(all map[string]AbandonedSite) { delete(all, site) })
+}
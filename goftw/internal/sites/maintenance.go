@@ -0,0 +1,106 @@
+package sites
+
+import (
+	"fmt"
+	"sort"
+
+	"goftw/internal/state"
+)
+
+// maintenanceState remembers the states of sites put into maintenance for an update,
+// so they can be restored even if goftw dies before the update finishes
+const maintenanceState = "maintenance.json"
+
+// siteModes is what maintenance orchestration changes on a site
+type siteModes struct {
+	Maintenance     bool `json:"maintenance"`
+	SchedulerPaused bool `json:"scheduler_paused"`
+}
+
+// EnterMaintenance puts the named sites into maintenance mode and pauses their schedulers,
+// remembering their previous states; sites an update does not touch keep serving. The
+// returned function puts the sites back into those states and must be called once the
+// update is over, even on failure.
+func EnterMaintenance(benchDir string, names []string) (func() error, error) {
+	// A previous run that died mid-update left its sites in maintenance
+	if err := RecoverMaintenance(); err != nil {
+		return nil, err
+	}
+
+	previous := map[string]siteModes{}
+	for _, site := range names {
+		cfg, err := readSiteConfig(benchDir, site)
+		if err != nil {
+			return nil, err
+		}
+		previous[site] = siteModes{Maintenance: truthy(cfg["maintenance_mode"]), SchedulerPaused: truthy(cfg["pause_scheduler"])}
+	}
+	if err := state.Save(maintenanceState, previous); err != nil {
+		return nil, fmt.Errorf("failed to record site states before maintenance: %v", err)
+	}
+
+	restore := func() error { return restoreModes(previous) }
+	for _, site := range sortedSites(previous) {
+		fmt.Printf("[SITES] Putting site %s into maintenance for the update\n", site)
+		if err := setModes(site, siteModes{Maintenance: true, SchedulerPaused: true}); err != nil {
+			_ = restore()
+			return nil, err
+		}
+	}
+	return restore, nil
+}
+
+// RecoverMaintenance restores the states recorded by an update that never finished
+func RecoverMaintenance() error {
+	var previous map[string]siteModes
+	if err := state.Load(maintenanceState, &previous); err != nil {
+		return err
+	}
+	if len(previous) == 0 {
+		return nil
+	}
+	fmt.Println("[SITES] Restoring site states left by an interrupted update")
+	return restoreModes(previous)
+}
+
+func restoreModes(previous map[string]siteModes) error {
+	var firstErr error
+	for _, site := range sortedSites(previous) {
+		fmt.Printf("[SITES] Restoring maintenance state of site %s\n", site)
+		if err := setModes(site, previous[site]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	return state.Save(maintenanceState, map[string]siteModes{})
+}
+
+// SetMaintenance switches maintenance mode and the scheduler pause of a site together
+func SetMaintenance(site string, on bool) error {
+	return setModes(site, siteModes{Maintenance: on, SchedulerPaused: on})
+}
+
+func setModes(site string, modes siteModes) error {
+	mode, scheduler := "off", "resume"
+	if modes.Maintenance {
+		mode = "on"
+	}
+	if modes.SchedulerPaused {
+		scheduler = "pause"
+	}
+	if err := ShortHandRunOnSite(site, "set-maintenance-mode", mode); err != nil {
+		return err
+	}
+	return ShortHandRunOnSite(site, "scheduler", scheduler)
+}
+
+func sortedSites(m map[string]siteModes) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -2,28 +2,79 @@ package sites
 
 import (
 	"fmt"
-	"goftw/internal/bench"
+	"goftw/internal/backup"
+	"goftw/internal/workers"
+	"time"
 )
 
 // Migrate runs bench Migrate
 func Migrate(site string) error {
 	fmt.Printf("[SITES] Migrating site: %s\n", site)
-	return ShortHandRunOnSite(site, "migrate")
+	if err := ShortHandRunOnSite(site, "migrate"); err != nil {
+		RecordError(site, "migrate", err)
+		return err
+	}
+	recordResult(site, func(r *SiteResult) { r.Migrated = true })
+	recordMigratedCommits(site)
+	return nil
 }
 
-// MigrateAll runs migrate for all provided sites
-func MigrateAll(benchDir string) error {
-	sites, err := bench.ListSites(benchDir)
+// MigrateSites migrates the given sites after an app update, up to parallel sites at a time. Each
+// site is backed up first; if any migration fails, every attempted site is restored from its backup,
+// apps are reset to previousCommits (recorded before the update), and a report is written.
+func MigrateSites(benchDir string, sites []string, parallel int, previousCommits map[string]string, dbRootUser, dbRootPass string) error {
+	started := time.Now().UTC()
+
+	// Back up every site before touching any, so a rollback always has something to restore
+	results := workers.RunSelf(sites, parallel, true, func(site string) []string {
+		return []string{"safety-backup", "migrate", site}
+	})
+	if failed := workers.Failed(results); len(failed) > 0 {
+		collectWorkerResults(results, "safety backup")
+		fmt.Printf("[ERROR] Not migrating any site, backup of %s failed: %v\n", failed[0].Name, failed[0].Err)
+		return fmt.Errorf("safety backup of site %s failed: %v", failed[0].Name, failed[0].Err)
+	}
+	backups, err := safetyBackupsSince(sites, "migrate", started)
 	if err != nil {
-		fmt.Printf("[ERROR] Failed to list current sites for migration: %v\n", err)
 		return err
 	}
 
+	results = workers.RunSelf(sites, parallel, true, func(site string) []string {
+		return []string{"migrate-site", site}
+	})
+	collectWorkerResults(results, "migrate")
+	failed := workers.Failed(results)
+	if len(failed) == 0 {
+		return nil
+	}
+
+	var attempted []string
+	for _, r := range results {
+		if !r.Skipped {
+			attempted = append(attempted, r.Name)
+		}
+	}
+	site, cause := failed[0].Name, failed[0].Err
+	fmt.Printf("[ERROR] Failed to migrate site %s: %v\n", site, cause)
+	report := rollbackMigration(benchDir, site, cause, attempted, backups, previousCommits, dbRootUser, dbRootPass)
+	return fmt.Errorf("migration of site %s failed and was rolled back (report: %s): %v", site, report, cause)
+}
+
+// safetyBackupsSince finds, for each site, the safety backup taken by the worker for step
+func safetyBackupsSince(sites []string, step string, since time.Time) (map[string]*backup.Entry, error) {
+	backups := map[string]*backup.Entry{}
 	for _, site := range sites {
-		if err := Migrate(site); err != nil {
-			fmt.Printf("[ERROR] Failed to migrate site %s: %v\n", site, err)
-			return err
+		entries, err := backup.List(site)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.Kind == "safety-"+step && !e.CreatedAt.Before(since.Truncate(time.Second)) {
+				entry := e
+				backups[site] = &entry
+				break
+			}
 		}
 	}
-	return nil
+	return backups, nil
 }
@@ -0,0 +1,179 @@
+package sites
+
+import (
+	"fmt"
+	"sort"
+
+	"goftw/internal/bench"
+	"goftw/internal/environ"
+	"goftw/internal/state"
+	"goftw/internal/utils"
+)
+
+// migratedCommitsState maps site -> app -> commit the site was last migrated at
+const migratedCommitsState = "migrated_commits.json"
+
+// recordMigratedCommits remembers the app commits a site's database now matches,
+// so `status` can tell whether the site has migrations pending. When apps are given,
+// only their commits are updated (e.g. after installing them).
+func recordMigratedCommits(site string, apps ...string) {
+	commits, err := bench.AppCommits(environ.GetBenchPath())
+	if err != nil {
+		fmt.Printf("[WARN] Could not record migrated commits for site %s: %v\n", site, err)
+		return
+	}
+	unlock, err := state.Lock(migratedCommitsState)
+	if err != nil {
+		fmt.Printf("[WARN] Could not record migrated commits for site %s: %v\n", site, err)
+		return
+	}
+	defer unlock()
+	all, err := loadMigratedCommits()
+	if err == nil {
+		switch {
+		case len(apps) == 0:
+			all[site] = commits
+		case all[site] != nil:
+			for _, app := range apps {
+				all[site][app] = commits[app]
+			}
+		default:
+			// The rest of the site was never recorded, its state stays unknown
+			return
+		}
+		err = state.Save(migratedCommitsState, all)
+	}
+	if err != nil {
+		fmt.Printf("[WARN] Could not record migrated commits for site %s: %v\n", site, err)
+	}
+}
+
+// forgetMigration records that the restored sites are back at the commits their backups were
+// taken at, so neither status nor the next update sees migrations that were rolled back.
+func forgetMigration(restored []string, commits map[string]string) error {
+	unlock, err := state.Lock(migratedCommitsState)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	all, err := loadMigratedCommits()
+	if err != nil {
+		return err
+	}
+	resetMigratedCommits(all, restored, commits)
+	return state.Save(migratedCommitsState, all)
+}
+
+// resetMigratedCommits sets the migrated commits of every site in restored to commits
+func resetMigratedCommits(all map[string]map[string]string, restored []string, commits map[string]string) {
+	for _, site := range restored {
+		all[site] = map[string]string{}
+		for app, commit := range commits {
+			all[site][app] = commit
+		}
+	}
+}
+
+func loadMigratedCommits() (map[string]map[string]string, error) {
+	all := map[string]map[string]string{}
+	if err := state.Load(migratedCommitsState, &all); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// pendingMigrations lists the installed apps whose checked out commit differs from the one
+// the site was last migrated at. known is false when the site was never migrated by goftw.
+func pendingMigrations(site string, installed []string, current map[string]string) (pending []string, known bool, err error) {
+	all, err := loadMigratedCommits()
+	if err != nil {
+		return nil, false, err
+	}
+	migrated, ok := all[site]
+	if !ok {
+		return nil, false, nil
+	}
+	return pendingApps(migrated, installed, current), true, nil
+}
+
+// pendingApps lists the installed apps whose current commit differs from the migrated one
+func pendingApps(migrated map[string]string, installed []string, current map[string]string) []string {
+	var pending []string
+	for _, app := range installed {
+		if commit, ok := current[app]; ok && migrated[app] != commit {
+			pending = append(pending, app)
+		}
+	}
+	return pending
+}
+
+// AffectedSites lists the sites of the bench an update of apps can touch: sites with one of
+// apps installed, and sites with migrations already pending.
+func AffectedSites(benchDir string, apps []string) ([]string, error) {
+	all, err := bench.ListSites(benchDir)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := bench.AppCommits(benchDir)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, site := range all {
+		info, err := ListApps(site)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list apps of site %s: %v", site, err)
+		}
+		installed := utils.ExtractAppNames(info)
+		pending, _, err := pendingMigrations(site, installed, commits)
+		if err != nil {
+			return nil, err
+		}
+		if len(pending) > 0 || len(utils.Intersect(installed, apps)) > 0 {
+			out = append(out, site)
+		}
+	}
+	return out, nil
+}
+
+// SitesToMigrate lists the sites of the bench that need a migration: sites whose installed
+// apps differ from the commits they were last migrated at and, for sites goftw never
+// migrated, sites with one of the moved apps installed.
+func SitesToMigrate(benchDir string, moved []string) ([]string, error) {
+	all, err := bench.ListSites(benchDir)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := bench.AppCommits(benchDir)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, site := range all {
+		info, err := ListApps(site)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list apps of site %s: %v", site, err)
+		}
+		installed := utils.ExtractAppNames(info)
+		pending, known, err := pendingMigrations(site, installed, commits)
+		if err != nil {
+			return nil, err
+		}
+		if len(pending) > 0 || (!known && len(utils.Intersect(installed, moved)) > 0) {
+			out = append(out, site)
+		}
+	}
+	return out, nil
+}
+
+// MovedApps lists the apps whose commit differs between two AppCommits snapshots
+func MovedApps(before, after map[string]string) []string {
+	var moved []string
+	for app, commit := range after {
+		if before[app] != commit {
+			moved = append(moved, app)
+		}
+	}
+	sort.Strings(moved)
+	return moved
+}
@@ -0,0 +1,44 @@
+package sites
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResetMigratedCommitsAfterRollback(t *testing.T) {
+	previous := map[string]string{"frappe": "f1", "erpnext": "e1"}
+	updated := map[string]string{"frappe": "f2", "erpnext": "e2"}
+	installed := []string{"frappe", "erpnext"}
+
+	// a.local and b.local migrated before c.local failed; a.local was restored, b.local was not
+	all := map[string]map[string]string{
+		"a.local": {"frappe": "f2", "erpnext": "e2"},
+		"b.local": {"frappe": "f2", "erpnext": "e2"},
+		"c.local": {"frappe": "f1", "erpnext": "e1"},
+	}
+	resetMigratedCommits(all, []string{"a.local", "c.local"}, previous)
+
+	tests := []struct {
+		site string
+		want []string
+	}{
+		{"a.local", nil},
+		{"b.local", []string{"frappe", "erpnext"}},
+		{"c.local", nil},
+	}
+	for _, tt := range tests {
+		// After the rollback the apps are back at the previous commits
+		if got := pendingApps(all[tt.site], installed, previous); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: pending after rollback = %v, want %v", tt.site, got, tt.want)
+		}
+	}
+
+	// The recorded commits are copies, later updates of the snapshot do not leak into them
+	previous["frappe"] = "f3"
+	if all["a.local"]["frappe"] != "f1" {
+		t.Errorf("a.local shares the commits map it was reset from")
+	}
+	if got := pendingApps(all["a.local"], installed, updated); !reflect.DeepEqual(got, installed) {
+		t.Errorf("a.local: pending at the new commits = %v, want %v", got, installed)
+	}
+}
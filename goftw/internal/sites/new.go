@@ -3,11 +3,78 @@ package sites
 import (
 	"fmt"
 	"goftw/internal/bench"
+	"goftw/internal/config"
+	"goftw/internal/secrets"
 )
 
-// New creates a new site
-func New(site, dbRootUser, dbRootPass string) error {
-	fmt.Printf("[SITES] Creating new site: %s\n", site)
-	_, err := bench.RunInBenchSwallowIO("new-site", site, "--db-root-username", dbRootUser, "--db-root-password", dbRootPass, "--admin-password", "admin")
+// New creates a new site using the creation options declared in instance.json
+func New(site config.InstanceSite, dbRootUser, dbRootPass string) error {
+	fmt.Printf("[SITES] Creating new site: %s\n", site.SiteName)
+	adminPass, err := adminPassword(site)
+	if err != nil {
+		fmt.Printf("[ERROR] Failed to resolve admin password for site %s: %v\n", site.SiteName, err)
+		return err
+	}
+
+	args := []string{"new-site", site.SiteName, "--db-root-username", dbRootUser, "--db-root-password", dbRootPass, "--admin-password", adminPass}
+	if site.DBName != "" {
+		args = append(args, "--db-name", site.DBName)
+	}
+	if site.DBType != "" {
+		args = append(args, "--db-type", site.DBType)
+	}
+	if site.DBHost != "" {
+		args = append(args, "--db-host", site.DBHost)
+	}
+	for _, app := range site.InstallApps {
+		if app != "frappe" {
+			args = append(args, "--install-app", app)
+		}
+	}
+	if site.SetDefault {
+		args = append(args, "--set-default")
+	}
+
+	if site.SetDefault {
+		// --set-default writes the bench-wide common_site_config.json
+		return bench.WithSharedLock(func() error {
+			_, err := bench.RunInBenchSwallowIO(args...)
+			return err
+		})
+	}
+	_, err = bench.RunInBenchSwallowIO(args...)
 	return err
 }
+
+// adminPassword resolves the admin password for a site: an explicit password, a secret
+// reference, a previously generated password, or a newly generated one stored in the secrets file.
+func adminPassword(site config.InstanceSite) (string, error) {
+	if site.AdminPassword != "" {
+		return site.AdminPassword, nil
+	}
+	if site.AdminPasswordSecret != "" {
+		return secrets.Resolve(site.AdminPasswordSecret)
+	}
+
+	key := adminPasswordKey(site.SiteName)
+	if pass, ok, err := secrets.Get(key); err != nil {
+		return "", err
+	} else if ok {
+		return pass, nil
+	}
+
+	pass, err := secrets.GeneratePassword(24)
+	if err != nil {
+		return "", err
+	}
+	if err := secrets.Set(key, pass); err != nil {
+		return "", err
+	}
+	fmt.Printf("[SITES] Generated admin password for site %s, stored under %q in secrets file\n", site.SiteName, key)
+	return pass, nil
+}
+
+// adminPasswordKey is the secrets file key holding a site's generated admin password
+func adminPasswordKey(siteName string) string {
+	return siteName + "/admin_password"
+}
@@ -0,0 +1,128 @@
+package sites
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"goftw/internal/bench"
+	"goftw/internal/config"
+	"goftw/internal/utils"
+)
+
+// Plan prints the changes a reconciliation run would make without applying any of them.
+func Plan(instanceCfg *config.InstanceConfig, benchDir string) error {
+	currentSites, err := bench.ListSites(benchDir)
+	if err != nil {
+		fmt.Printf("[ERROR] Failed to list current sites: %v\n", err)
+		return err
+	}
+
+	drift := 0
+	for _, site := range currentSites {
+		if !siteExistsInCfx(site, instanceCfg) {
+			if isPreviousName(instanceCfg, site) {
+				continue // reported as a rename below, or kept if the new name already exists
+			}
+			if IsProtected(instanceCfg, site) {
+				fmt.Printf("[PLAN] %s: abandoned site (kept, protected)\n", site)
+			} else if instanceCfg.DropAbandonedSites && instanceCfg.AbandonedSites.Policy == "archive" {
+				fmt.Printf("[PLAN] %s: archive abandoned site, drop after the grace period\n", site)
+				drift++
+			} else if instanceCfg.DropAbandonedSites {
+				fmt.Printf("[PLAN] %s: drop abandoned site\n", site)
+				drift++
+			} else {
+				fmt.Printf("[PLAN] %s: abandoned site (kept, drop_abandoned_sites is false)\n", site)
+			}
+		}
+	}
+
+	for _, site := range instanceCfg.InstanceSites {
+		if old := renamePending(site, benchDir); old != "" {
+			fmt.Printf("[PLAN] %s: rename site from %s\n", site.SiteName, old)
+			drift++
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(benchDir, "sites", site.SiteName)); os.IsNotExist(err) {
+			if site.RestoreFrom != "" {
+				fmt.Printf("[PLAN] %s: restore site from %s\n", site.SiteName, site.RestoreFrom)
+			} else if site.CloneFrom != "" {
+				fmt.Printf("[PLAN] %s: clone site from %s\n", site.SiteName, site.CloneFrom)
+			} else {
+				fmt.Printf("[PLAN] %s: create site with apps %v\n", site.SiteName, site.Apps)
+			}
+			drift++
+			continue
+		}
+
+		currentAppsInfo, err := ListApps(site.SiteName)
+		if err != nil {
+			fmt.Printf("[ERROR] Failed to list apps for site %s: %v\n", site.SiteName, err)
+			return err
+		}
+		currentAppNames := utils.ExtractAppNames(currentAppsInfo)
+		expectedApps := append([]string{}, site.Apps...)
+		sort.Strings(currentAppNames)
+		sort.Strings(expectedApps)
+		for _, app := range utils.Difference(expectedApps, currentAppNames) {
+			if app != "frappe" {
+				fmt.Printf("[PLAN] %s: install app %s\n", site.SiteName, app)
+				drift++
+			}
+		}
+		for _, app := range utils.Difference(currentAppNames, expectedApps) {
+			if app != "frappe" {
+				fmt.Printf("[PLAN] %s: uninstall app %s\n", site.SiteName, app)
+				drift++
+			}
+		}
+
+		changes, err := SiteConfigDrift(site, benchDir)
+		if err != nil {
+			fmt.Printf("[ERROR] Failed to compute site_config drift for site %s: %v\n", site.SiteName, err)
+			return err
+		}
+		for _, c := range changes {
+			fmt.Printf("[PLAN] %s: site_config %s\n", site.SiteName, c)
+			drift++
+		}
+
+		currentDomains, err := ListDomains(site.SiteName, benchDir)
+		if err != nil {
+			fmt.Printf("[ERROR] Failed to read domains for site %s: %v\n", site.SiteName, err)
+			return err
+		}
+		expectedDomains := append([]string{}, site.Domains...)
+		sort.Strings(currentDomains)
+		sort.Strings(expectedDomains)
+		for _, domain := range utils.Difference(expectedDomains, currentDomains) {
+			fmt.Printf("[PLAN] %s: add domain %s\n", site.SiteName, domain)
+			drift++
+		}
+		for _, domain := range utils.Difference(currentDomains, expectedDomains) {
+			fmt.Printf("[PLAN] %s: remove domain %s\n", site.SiteName, domain)
+			drift++
+		}
+
+		if site.SchedulerEnabled != nil {
+			enabled, err := schedulerEnabled(site.SiteName)
+			if err != nil {
+				fmt.Printf("[ERROR] Failed to read scheduler status of site %s: %v\n", site.SiteName, err)
+				return err
+			}
+			if enabled != *site.SchedulerEnabled {
+				fmt.Printf("[PLAN] %s: %s scheduler\n", site.SiteName, map[bool]string{true: "enable", false: "disable"}[*site.SchedulerEnabled])
+				drift++
+			}
+		}
+	}
+
+	if drift == 0 {
+		fmt.Println("[PLAN] No changes. Sites match instance.json")
+	} else {
+		fmt.Printf("[PLAN] %d change(s) pending\n", drift)
+	}
+	return nil
+}
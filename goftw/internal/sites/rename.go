@@ -0,0 +1,156 @@
+package sites
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"goftw/internal/bench"
+	"goftw/internal/config"
+	"goftw/internal/secrets"
+	"goftw/internal/state"
+)
+
+// RenameSites moves sites found on disk under one of their previous_names to their
+// declared name, so they are kept instead of dropped as abandoned and recreated blank.
+func RenameSites(instanceCfg *config.InstanceConfig, benchDir string) error {
+	for _, site := range instanceCfg.InstanceSites {
+		if siteDirExists(benchDir, site.SiteName) {
+			continue
+		}
+		for _, old := range site.PreviousNames {
+			if !siteDirExists(benchDir, old) {
+				continue
+			}
+			if err := renameSite(benchDir, old, site.SiteName); err != nil {
+				fmt.Printf("[ERROR] Failed to rename site %s to %s: %v\n", old, site.SiteName, err)
+				RecordError(site.SiteName, "rename", err)
+				return err
+			}
+			recordResult(site.SiteName, func(r *SiteResult) { r.RenamedFrom = old })
+			break
+		}
+	}
+	return nil
+}
+
+// renamePending returns the previous name a declared site would be renamed from, if any
+func renamePending(site config.InstanceSite, benchDir string) string {
+	if siteDirExists(benchDir, site.SiteName) {
+		return ""
+	}
+	for _, old := range site.PreviousNames {
+		if siteDirExists(benchDir, old) {
+			return old
+		}
+	}
+	return ""
+}
+
+// isPreviousName reports whether an unlisted site is the old name of a declared site.
+// Such a site is never treated as abandoned, even when the rename cannot happen.
+func isPreviousName(instanceCfg *config.InstanceConfig, name string) bool {
+	for _, site := range instanceCfg.InstanceSites {
+		for _, old := range site.PreviousNames {
+			if old == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func renameSite(benchDir, old, name string) error {
+	fmt.Printf("[SITES] Renaming site %s to %s\n", old, name)
+	sitesDir := filepath.Join(benchDir, "sites")
+
+	// The database keeps its name (db_name in site_config.json), only the folder and the
+	// references to the hostname change
+	err := bench.WithSharedLock(func() error {
+		if err := os.Rename(filepath.Join(sitesDir, old), filepath.Join(sitesDir, name)); err != nil {
+			return err
+		}
+		return renameDefaultSite(sitesDir, old, name)
+	})
+	if err != nil {
+		return err
+	}
+
+	cfg, err := readSiteConfig(benchDir, name)
+	if err != nil {
+		return err
+	}
+	if hostName, ok := cfg["host_name"].(string); ok && strings.Contains(hostName, old) {
+		cfg["host_name"] = strings.Replace(hostName, old, name, 1)
+		if err := writeSiteConfig(benchDir, name, cfg); err != nil {
+			return err
+		}
+	}
+
+	// Carry over what goftw remembers about the site
+	for _, file := range []string{managedSiteConfigState, migratedCommitsState} {
+		if err := renameStateKey(file, old, name); err != nil {
+			fmt.Printf("[WARN] Could not move %s state of site %s: %v\n", file, old, err)
+		}
+	}
+	if err := renameSecret(adminPasswordKey(old), adminPasswordKey(name)); err != nil {
+		fmt.Printf("[WARN] Could not move admin password of site %s: %v\n", old, err)
+	}
+
+	// Cached documents and sessions still carry the old hostname
+	if err := ShortHandRunOnSite(name, "clear-cache"); err != nil {
+		fmt.Printf("[WARN] Failed to clear cache of renamed site %s: %v\n", name, err)
+	}
+	return nil
+}
+
+// renameDefaultSite points currentsite.txt at the new name when the old one was the default
+func renameDefaultSite(sitesDir, old, name string) error {
+	path := filepath.Join(sitesDir, "currentsite.txt")
+	data, err := os.ReadFile(path)
+	if err != nil || strings.TrimSpace(string(data)) != old {
+		return nil
+	}
+	return os.WriteFile(path, []byte(name), 0644)
+}
+
+// renameStateKey moves the entry of a site in a state file keyed by site name
+func renameStateKey(file, old, name string) error {
+	unlock, err := state.Lock(file)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	all := map[string]json.RawMessage{}
+	if err := state.Load(file, &all); err != nil {
+		return err
+	}
+	v, ok := all[old]
+	if !ok {
+		return nil
+	}
+	all[name] = v
+	delete(all, old)
+	return state.Save(file, all)
+}
+
+func renameSecret(old, name string) error {
+	return secrets.Update(func(store map[string]string) error {
+		v, ok := store[old]
+		if !ok {
+			return nil
+		}
+		if _, exists := store[name]; !exists {
+			store[name] = v
+		}
+		delete(store, old)
+		return nil
+	})
+}
+
+func siteDirExists(benchDir, name string) bool {
+	_, err := os.Stat(siteConfigPath(benchDir, name))
+	return err == nil
+}
@@ -0,0 +1,127 @@
+package sites
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"goftw/internal/backup"
+	"goftw/internal/bench"
+	"goftw/internal/config"
+	"goftw/internal/utils"
+)
+
+// Restore creates a missing site from the backup named by its restore_from option.
+// The backup's checksums are verified and its apps must all be declared for the site.
+func Restore(site config.InstanceSite, storage *config.ObjectStorageConfig, benchDir, dbRootUser, dbRootPass string) error {
+	fmt.Printf("[SITES] Restoring site %s from %s\n", site.SiteName, site.RestoreFrom)
+	dir, err := backup.Fetch(site.RestoreFrom, storage)
+	if err != nil {
+		fmt.Printf("[ERROR] Failed to fetch backup for site %s: %v\n", site.SiteName, err)
+		return err
+	}
+	manifest, err := backup.VerifyDir(dir)
+	if err != nil {
+		fmt.Printf("[ERROR] Backup for site %s failed verification: %v\n", site.SiteName, err)
+		return err
+	}
+
+	if err := checkRestoreApps(site, manifest.Apps); err != nil {
+		fmt.Printf("[ERROR] Backup for site %s is incompatible: %v\n", site.SiteName, err)
+		return err
+	}
+	// Every app in the backup must be present in the bench before its data is restored
+	if err := fetchMissingApps(manifest.Apps, benchDir); err != nil {
+		fmt.Printf("[ERROR] Failed to fetch apps required by backup of site %s: %v\n", site.SiteName, err)
+		return err
+	}
+
+	return restoreSet(site.SiteName, dir, dbRootUser, dbRootPass)
+}
+
+// restoreSet runs `bench restore` for a site from the files of a backup set directory
+func restoreSet(siteName, dir, dbRootUser, dbRootPass string) error {
+	files, err := backupFiles(dir)
+	if err != nil {
+		return err
+	}
+	args := []string{"--site", siteName, "restore", files["database"],
+		"--db-root-username", dbRootUser, "--db-root-password", dbRootPass, "--force"}
+	if f, ok := files["public"]; ok {
+		args = append(args, "--with-public-files", f)
+	}
+	if f, ok := files["private"]; ok {
+		args = append(args, "--with-private-files", f)
+	}
+	if f, ok := files["site_config"]; ok {
+		// Encrypted fields (passwords, API keys) are unreadable without the original key
+		if key := encryptionKey(f); key != "" {
+			args = append(args, "--encryption-key", key)
+		}
+	}
+
+	if err := bench.RunInBenchPrintIO(args...); err != nil {
+		fmt.Printf("[ERROR] bench restore failed for site %s: %v\n", siteName, err)
+		return err
+	}
+	fmt.Printf("[SITES] Restored site %s from %s\n", siteName, dir)
+	return nil
+}
+
+// checkRestoreApps fails if the backup contains apps the site does not declare,
+// since app alignment would uninstall them and drop their data right after the restore.
+func checkRestoreApps(site config.InstanceSite, backupApps []string) error {
+	if backupApps == nil {
+		return fmt.Errorf("backup manifest does not record the site's apps")
+	}
+	declared := append([]string{"frappe"}, site.Apps...)
+	sort.Strings(declared)
+	if extra := utils.Difference(backupApps, declared); len(extra) > 0 {
+		return fmt.Errorf("backup contains apps not declared for the site: %s", strings.Join(extra, ", "))
+	}
+	return nil
+}
+
+// backupFiles classifies the files of a `bench backup` set by their suffix
+func backupFiles(dir string) (map[string]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]string{}
+	for _, p := range paths {
+		name := filepath.Base(p)
+		switch {
+		case strings.HasSuffix(name, "-database.sql.gz"), strings.HasSuffix(name, "-database.sql"):
+			files["database"] = p
+		case strings.HasSuffix(name, "-private-files.tar"), strings.HasSuffix(name, "-private-files.tgz"):
+			files["private"] = p
+		case strings.HasSuffix(name, "-files.tar"), strings.HasSuffix(name, "-files.tgz"):
+			files["public"] = p
+		case strings.HasSuffix(name, "-site_config_backup.json"):
+			files["site_config"] = p
+		}
+	}
+	if files["database"] == "" {
+		return nil, fmt.Errorf("no database dump found in %s", dir)
+	}
+	return files, nil
+}
+
+// encryptionKey reads encryption_key from a backed up site_config.json
+func encryptionKey(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	var cfg struct {
+		EncryptionKey string `json:"encryption_key"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return ""
+	}
+	return cfg.EncryptionKey
+}
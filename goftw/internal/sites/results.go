@@ -0,0 +1,226 @@
+package sites
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"goftw/internal/state"
+	"goftw/internal/utils"
+	"goftw/internal/workers"
+)
+
+// ResultFileEnv names the file a worker process writes its site results to
+const ResultFileEnv = "GOFTW_RESULT_FILE"
+
+// SiteResult records what a run did to one site, step by step
+type SiteResult struct {
+	Site          string   `json:"site"`
+	Created       bool     `json:"created,omitempty"`
+	RenamedFrom   string   `json:"renamed_from,omitempty"`
+	ClonedFrom    string   `json:"cloned_from,omitempty"`
+	Restored      bool     `json:"restored,omitempty"`
+	Dropped       bool     `json:"dropped,omitempty"`
+	Archived      bool     `json:"archived,omitempty"`
+	AppsInstalled []string `json:"apps_installed,omitempty"`
+	AppsRemoved   []string `json:"apps_removed,omitempty"`
+	ConfigChanged bool     `json:"config_changed,omitempty"`
+	DomainsChange bool     `json:"domains_changed,omitempty"`
+	Migrated      bool     `json:"migrated,omitempty"`
+	Errors        []string `json:"errors,omitempty"`
+}
+
+// Failed reports whether any step of the site failed
+func (r *SiteResult) Failed() bool {
+	return len(r.Errors) > 0
+}
+
+var (
+	resultsMu sync.Mutex
+	results   = map[string]*SiteResult{}
+)
+
+// recordResult updates the result of a site under the results lock
+func recordResult(site string, update func(r *SiteResult)) {
+	resultsMu.Lock()
+	defer resultsMu.Unlock()
+	r, ok := results[site]
+	if !ok {
+		r = &SiteResult{Site: site}
+		results[site] = r
+	}
+	update(r)
+}
+
+// RecordError records a failed step for a site
+func RecordError(site, step string, err error) {
+	recordResult(site, func(r *SiteResult) {
+		r.Errors = append(r.Errors, fmt.Sprintf("%s: %v", step, err))
+	})
+}
+
+// ResetResults forgets the recorded results, so a long-running process can summarize each run separately
+func ResetResults() {
+	resultsMu.Lock()
+	defer resultsMu.Unlock()
+	results = map[string]*SiteResult{}
+}
+
+// Results returns the recorded site results sorted by site name
+func Results() []SiteResult {
+	resultsMu.Lock()
+	defer resultsMu.Unlock()
+	out := make([]SiteResult, 0, len(results))
+	for _, r := range results {
+		out = append(out, *r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Site < out[j].Site })
+	return out
+}
+
+// WriteResults saves the results recorded by this process, for a worker's parent to merge.
+func WriteResults(path string) error {
+	data, err := json.Marshal(Results())
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// mergeResults folds the results written by a worker process into this process's results.
+func mergeResults(data []byte) error {
+	var worker []SiteResult
+	if err := json.Unmarshal(data, &worker); err != nil {
+		return err
+	}
+	for _, w := range worker {
+		recordResult(w.Site, func(r *SiteResult) {
+			r.Created = r.Created || w.Created
+			if w.RenamedFrom != "" {
+				r.RenamedFrom = w.RenamedFrom
+			}
+			if w.ClonedFrom != "" {
+				r.ClonedFrom = w.ClonedFrom
+			}
+			r.Restored = r.Restored || w.Restored
+			r.Dropped = r.Dropped || w.Dropped
+			r.Archived = r.Archived || w.Archived
+			r.AppsInstalled = append(r.AppsInstalled, w.AppsInstalled...)
+			r.AppsRemoved = append(r.AppsRemoved, w.AppsRemoved...)
+			r.ConfigChanged = r.ConfigChanged || w.ConfigChanged
+			r.DomainsChange = r.DomainsChange || w.DomainsChange
+			r.Migrated = r.Migrated || w.Migrated
+			r.Errors = append(r.Errors, w.Errors...)
+		})
+	}
+	return nil
+}
+
+// healthState records the outcome of the latest run, which status reports while the services run
+const healthState = "health.json"
+
+// LastRun is the outcome of the latest reconciliation run
+type LastRun struct {
+	Time        time.Time `json:"time"`
+	Failed      int       `json:"failed"`
+	FailedSites []string  `json:"failed_sites,omitempty"`
+	Report      string    `json:"report"`
+}
+
+// LoadLastRun returns the outcome of the latest reconciliation run, nil before the first one
+func LoadLastRun() (*LastRun, error) {
+	var run *LastRun
+	if err := state.Load(healthState, &run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// Summarize prints a table of every site's outcome, writes the JSON run report and
+// returns the number of sites with failures.
+func Summarize() int {
+	all := Results()
+	failed := 0
+	var failedSites []string
+
+	fmt.Println("[SUMMARY] Site results:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SITE\tCREATED\tINSTALLED\tREMOVED\tMIGRATED\tSTATUS")
+	for _, r := range all {
+		created := utils.YesNo(r.Created)
+		if r.Restored {
+			created = "restored"
+		} else if r.ClonedFrom != "" {
+			created = "cloned from " + r.ClonedFrom
+		} else if r.RenamedFrom != "" {
+			created = "renamed from " + r.RenamedFrom
+		}
+		status := "ok"
+		if r.Dropped {
+			status = "dropped"
+		} else if r.Archived {
+			status = "archived"
+		}
+		if r.Failed() {
+			status = "FAILED: " + strings.Join(r.Errors, "; ")
+			failed++
+			failedSites = append(failedSites, r.Site)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Site, created, list(r.AppsInstalled), list(r.AppsRemoved), utils.YesNo(r.Migrated), status)
+	}
+	w.Flush()
+
+	report := struct {
+		Time   time.Time    `json:"time"`
+		Failed int          `json:"failed"`
+		Sites  []SiteResult `json:"sites"`
+	}{time.Now(), failed, all}
+	name := "reports/run-" + report.Time.UTC().Format("20060102T150405Z") + ".json"
+	if err := state.Save(name, report); err != nil {
+		fmt.Printf("[ERROR] Failed to write run report: %v\n", err)
+	} else {
+		fmt.Printf("[SUMMARY] Report written to %s\n", state.Path(name))
+	}
+	// Services keep running after a failed run, so status reads the outcome from here
+	health := LastRun{Time: report.Time, Failed: failed, FailedSites: failedSites, Report: state.Path(name)}
+	if err := state.Save(healthState, health); err != nil {
+		fmt.Printf("[ERROR] Failed to write %s: %v\n", state.Path(healthState), err)
+	}
+	return failed
+}
+
+func list(items []string) string {
+	if len(items) == 0 {
+		return "-"
+	}
+	return strings.Join(items, ",")
+}
+
+// collectWorkerResults merges the results written by worker processes and records
+// an error for any worker that failed without recording one itself.
+func collectWorkerResults(jobs []workers.Result, step string) {
+	for _, job := range jobs {
+		if job.Skipped {
+			RecordError(job.Name, step, fmt.Errorf("skipped after an earlier failure"))
+			continue
+		}
+		if job.ResultData != nil {
+			if err := mergeResults(job.ResultData); err != nil {
+				fmt.Printf("[WARN] Could not read results of %s worker for site %s: %v\n", step, job.Name, err)
+			}
+		}
+		if job.Err == nil {
+			continue
+		}
+		recorded := false
+		recordResult(job.Name, func(r *SiteResult) { recorded = r.Failed() })
+		if !recorded {
+			RecordError(job.Name, step, job.Err)
+		}
+	}
+}
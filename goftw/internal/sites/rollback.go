@@ -0,0 +1,89 @@
+package sites
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"goftw/internal/backup"
+	"goftw/internal/bench"
+	"goftw/internal/state"
+)
+
+// MigrationReport records what happened when a failed migration was rolled back
+type MigrationReport struct {
+	Time            time.Time         `json:"time"`
+	FailedSite      string            `json:"failed_site"`
+	Error           string            `json:"error"`
+	RestoredSites   []string          `json:"restored_sites"`
+	UnrestoredSites []string          `json:"unrestored_sites,omitempty"`
+	PreviousCommits map[string]string `json:"previous_commits"` // app commits before the update
+	ResetApps       map[string]string `json:"reset_apps"`       // app -> "<new commit> -> <previous commit>"
+	RollbackErrors  []string          `json:"rollback_errors,omitempty"`
+}
+
+// rollbackMigration restores the attempted sites from their pre-migration backups, resets apps
+// to the commits recorded before the update, and writes a report. It returns the report path.
+func rollbackMigration(benchDir, failedSite string, cause error, attempted []string, backups map[string]*backup.Entry, previousCommits map[string]string, dbRootUser, dbRootPass string) string {
+	fmt.Printf("[ROLLBACK] Rolling back migration after failure on site %s\n", failedSite)
+	report := MigrationReport{
+		Time:            time.Now(),
+		FailedSite:      failedSite,
+		Error:           cause.Error(),
+		PreviousCommits: previousCommits,
+		ResetApps:       map[string]string{},
+	}
+
+	for _, site := range attempted {
+		entry := backups[site]
+		if entry == nil {
+			fmt.Printf("[ROLLBACK] No backup of site %s to restore (safety backups disabled)\n", site)
+			report.UnrestoredSites = append(report.UnrestoredSites, site)
+			continue
+		}
+		if err := restoreSet(site, entry.Dir, dbRootUser, dbRootPass); err != nil {
+			report.UnrestoredSites = append(report.UnrestoredSites, site)
+			report.RollbackErrors = append(report.RollbackErrors, fmt.Sprintf("restore %s: %v", site, err))
+			continue
+		}
+		report.RestoredSites = append(report.RestoredSites, site)
+	}
+	// The restored databases match the previous commits again, whatever was recorded meanwhile
+	if len(report.RestoredSites) > 0 {
+		if err := forgetMigration(report.RestoredSites, previousCommits); err != nil {
+			report.RollbackErrors = append(report.RollbackErrors, fmt.Sprintf("record migrated commits: %v", err))
+		}
+	}
+
+	apps := make([]string, 0, len(previousCommits))
+	for app := range previousCommits {
+		apps = append(apps, app)
+	}
+	sort.Strings(apps)
+	for _, app := range apps {
+		previous := previousCommits[app]
+		current, err := bench.AppCommit(benchDir, app)
+		if err != nil || current == previous {
+			continue
+		}
+		if err := bench.ResetApp(benchDir, app, previous); err != nil {
+			report.RollbackErrors = append(report.RollbackErrors, fmt.Sprintf("reset %s: %v", app, err))
+			continue
+		}
+		report.ResetApps[app] = current + " -> " + previous
+	}
+	if len(report.ResetApps) > 0 {
+		// Assets were built from the newer code
+		if err := bench.WithSharedLock(func() error { return bench.RunInBenchPrintIO("build") }); err != nil {
+			report.RollbackErrors = append(report.RollbackErrors, fmt.Sprintf("bench build: %v", err))
+		}
+	}
+
+	name := "reports/migration-rollback-" + report.Time.UTC().Format("20060102T150405Z") + ".json"
+	if err := state.Save(name, report); err != nil {
+		fmt.Printf("[ERROR] Failed to write rollback report: %v\n", err)
+	}
+	fmt.Printf("[ROLLBACK] Restored sites: %v, unrestored: %v, reset apps: %v, errors: %d\n",
+		report.RestoredSites, report.UnrestoredSites, report.ResetApps, len(report.RollbackErrors))
+	return state.Path(name)
+}
@@ -0,0 +1,50 @@
+package sites
+
+import (
+	"fmt"
+	"strings"
+
+	"goftw/internal/bench"
+	"goftw/internal/config"
+)
+
+// CheckoutScheduler enables or disables a site's scheduler as declared by scheduler_enabled
+func CheckoutScheduler(site config.InstanceSite) error {
+	if site.SchedulerEnabled == nil {
+		return nil
+	}
+	enabled, err := schedulerEnabled(site.SiteName)
+	if err != nil {
+		return err
+	}
+	if enabled == *site.SchedulerEnabled {
+		return nil
+	}
+	action := "disable"
+	if *site.SchedulerEnabled {
+		action = "enable"
+	}
+	fmt.Printf("[SITES] Scheduler %s for site %s\n", action, site.SiteName)
+	if err := ShortHandRunOnSite(site.SiteName, "scheduler", action); err != nil {
+		return err
+	}
+	recordResult(site.SiteName, func(r *SiteResult) { r.ConfigChanged = true })
+	return nil
+}
+
+// schedulerEnabled asks frappe whether the scheduler of a site is enabled.
+// A paused scheduler still counts as enabled.
+func schedulerEnabled(site string) (bool, error) {
+	out, err := bench.RunInBenchSwallowIO("--site", site, "scheduler", "status")
+	if err != nil {
+		return false, fmt.Errorf("%v: %s", err, strings.TrimSpace(out))
+	}
+	out = strings.ToLower(out)
+	switch {
+	case strings.Contains(out, "disabled"):
+		return false, nil
+	case strings.Contains(out, "enabled"):
+		return true, nil
+	}
+	return false, fmt.Errorf("unexpected scheduler status output: %s", strings.TrimSpace(out))
+}
@@ -0,0 +1,234 @@
+package sites
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"goftw/internal/config"
+	"goftw/internal/state"
+)
+
+const managedSiteConfigState = "managed_site_config.json"
+
+var (
+	// Keys written by bench itself that goftw must never manage
+	reservedSiteConfigKeys = map[string]struct{}{
+		"db_name":     {},
+		"db_password": {},
+		"db_type":     {},
+		"domains":     {}, // managed through InstanceSite.Domains
+	}
+)
+
+// ConfigChange describes a single drifted key in a site's site_config.json
+type ConfigChange struct {
+	Key    string
+	Action string // "add", "change" or "remove"
+	Old    any
+	New    any
+}
+
+// String renders the change the same way for plan output and logs
+func (c ConfigChange) String() string {
+	switch c.Action {
+	case "add":
+		return fmt.Sprintf("+ %s = %s", c.Key, jsonValue(c.New))
+	case "remove":
+		return fmt.Sprintf("- %s (was %s)", c.Key, jsonValue(c.Old))
+	}
+	return fmt.Sprintf("~ %s: %s -> %s", c.Key, jsonValue(c.Old), jsonValue(c.New))
+}
+
+// SiteConfigDrift compares the declared site_config against the site's site_config.json
+func SiteConfigDrift(site config.InstanceSite, benchDir string) ([]ConfigChange, error) {
+	current, err := readSiteConfig(benchDir, site.SiteName)
+	if err != nil {
+		return nil, err
+	}
+	managed, err := loadManagedKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []ConfigChange
+	for _, key := range sortedKeys(site.SiteConfig) {
+		if _, ok := reservedSiteConfigKeys[key]; ok {
+			fmt.Printf("[WARN] Site %s: refusing to manage reserved site_config key %s\n", site.SiteName, key)
+			continue
+		}
+		want := normalizeJSON(site.SiteConfig[key])
+		have, ok := current[key]
+		if !ok {
+			changes = append(changes, ConfigChange{Key: key, Action: "add", New: want})
+		} else if !reflect.DeepEqual(have, want) {
+			changes = append(changes, ConfigChange{Key: key, Action: "change", Old: have, New: want})
+		}
+	}
+
+	if site.PruneSiteConfig {
+		for _, key := range managed[site.SiteName] {
+			if _, declared := site.SiteConfig[key]; declared {
+				continue
+			}
+			if have, ok := current[key]; ok {
+				changes = append(changes, ConfigChange{Key: key, Action: "remove", Old: have})
+			}
+		}
+	}
+	return changes, nil
+}
+
+// CheckoutSiteConfig reconciles the declared site_config keys into the site's site_config.json,
+// leaving keys goftw does not manage untouched.
+func CheckoutSiteConfig(site config.InstanceSite, benchDir string) error {
+	changes, err := SiteConfigDrift(site, benchDir)
+	if err != nil {
+		fmt.Printf("[ERROR] Failed to compute site_config drift for site %s: %v\n", site.SiteName, err)
+		return err
+	}
+
+	if len(changes) > 0 {
+		current, err := readSiteConfig(benchDir, site.SiteName)
+		if err != nil {
+			return err
+		}
+		for _, c := range changes {
+			fmt.Printf("[CONFIG] %s: %s\n", site.SiteName, c)
+			if c.Action == "remove" {
+				delete(current, c.Key)
+			} else {
+				current[c.Key] = c.New
+			}
+		}
+		if err := writeSiteConfig(benchDir, site.SiteName, current); err != nil {
+			fmt.Printf("[ERROR] Failed to write site_config.json for site %s: %v\n", site.SiteName, err)
+			return err
+		}
+		recordResult(site.SiteName, func(r *SiteResult) { r.ConfigChanged = true })
+	}
+
+	return recordManagedKeys(site)
+}
+
+// recordManagedKeys remembers which keys goftw manages for a site so they can be pruned later
+func recordManagedKeys(site config.InstanceSite) error {
+	// Parallel workers record their own sites into the same file
+	unlock, err := state.Lock(managedSiteConfigState)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	managed, err := loadManagedKeys()
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(site.SiteConfig))
+	for _, key := range sortedKeys(site.SiteConfig) {
+		if _, ok := reservedSiteConfigKeys[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	if !site.PruneSiteConfig {
+		// Without pruning, keys dropped from instance.json stay managed until pruning is enabled
+		keys = union(keys, managed[site.SiteName])
+	}
+	if reflect.DeepEqual(keys, managed[site.SiteName]) {
+		return nil
+	}
+	if len(keys) == 0 {
+		delete(managed, site.SiteName)
+	} else {
+		managed[site.SiteName] = keys
+	}
+	return state.Save(managedSiteConfigState, managed)
+}
+
+func loadManagedKeys() (map[string][]string, error) {
+	managed := map[string][]string{}
+	if err := state.Load(managedSiteConfigState, &managed); err != nil {
+		return nil, err
+	}
+	return managed, nil
+}
+
+func siteConfigPath(benchDir, siteName string) string {
+	return filepath.Join(benchDir, "sites", siteName, "site_config.json")
+}
+
+// readSiteConfig reads a site's site_config.json into a generic map
+func readSiteConfig(benchDir, siteName string) (map[string]any, error) {
+	data, err := os.ReadFile(siteConfigPath(benchDir, siteName))
+	if err != nil {
+		return nil, err
+	}
+	cfg := map[string]any{}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse site_config.json for %s: %v", siteName, err)
+	}
+	return cfg, nil
+}
+
+// writeSiteConfig atomically replaces a site's site_config.json, keeping its permissions
+func writeSiteConfig(benchDir, siteName string, cfg map[string]any) error {
+	path := siteConfigPath(benchDir, siteName)
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", " ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".goftw.tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// normalizeJSON round-trips a value so that it compares equal to values decoded from disk
+func normalizeJSON(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+func jsonValue(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func union(a, b []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, x := range append(append([]string{}, a...), b...) {
+		if !seen[x] {
+			seen[x] = true
+			out = append(out, x)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
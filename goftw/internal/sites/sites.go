@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"goftw/internal/bench"
 	"goftw/internal/config"
+	"goftw/internal/workers"
 	"os"
 	"path/filepath"
 )
@@ -18,8 +19,13 @@ func ShortHandRunOnSite(site string, args ...string) error {
 	return nil
 }
 
-// CheckoutSites orchestrates all site operations
+// CheckoutSites orchestrates all site operations. Sites are reconciled in separate worker
+// processes, up to max_parallel_sites at a time.
 func CheckoutSites(instanceCfg *config.InstanceConfig, benchDir, dbRootUser, dbRootPass string) error {
+	if err := RenameSites(instanceCfg, benchDir); err != nil {
+		return err
+	}
+
 	currentSites, err := bench.ListSites(benchDir)
 	if err != nil {
 		fmt.Printf("[ERROR] Failed to list current sites: %v\n", err)
@@ -31,24 +37,98 @@ func CheckoutSites(instanceCfg *config.InstanceConfig, benchDir, dbRootUser, dbR
 		return err
 	}
 
+	names := make([]string, 0, len(instanceCfg.InstanceSites))
 	for _, site := range instanceCfg.InstanceSites {
-		if err := CheckoutSite(site, benchDir, dbRootUser, dbRootPass); err != nil {
-			fmt.Printf("[ERROR] Failed to entirely checkout site %s: %v\n", site.SiteName, err)
-			return err
+		names = append(names, site.SiteName)
+	}
+	return CheckoutSiteNames(instanceCfg, names)
+}
+
+// CheckoutSiteNames reconciles only the named sites from instance.json, each in a worker process.
+func CheckoutSiteNames(instanceCfg *config.InstanceConfig, names []string) error {
+	failFast := instanceCfg.OnError != "continue"
+
+	var jobs []workers.Result
+	for _, batch := range cloneBatches(instanceCfg, names) {
+		if len(batch) == 0 {
+			continue
+		}
+		if failFast && len(workers.Failed(jobs)) > 0 {
+			for _, name := range batch {
+				jobs = append(jobs, workers.Result{Name: name, Skipped: true})
+			}
+			continue
+		}
+		jobs = append(jobs, workers.RunSelf(batch, instanceCfg.MaxParallelSites, failFast, func(name string) []string {
+			return []string{"checkout-site", name}
+		})...)
+	}
+	collectWorkerResults(jobs, "checkout")
+	if failed := workers.Failed(jobs); len(failed) > 0 {
+		fmt.Printf("[ERROR] Failed to entirely checkout %d site(s), first: %s: %v\n", len(failed), failed[0].Name, failed[0].Err)
+		if failFast {
+			return fmt.Errorf("checkout of site %s failed: %v", failed[0].Name, failed[0].Err)
 		}
 	}
 
 	return nil
 }
 
+// CheckoutSiteByName reconciles a single site from instance.json, including its domains.
+// It is what each checkout worker process runs.
+func CheckoutSiteByName(instanceCfg *config.InstanceConfig, name, benchDir, dbRootUser, dbRootPass string) error {
+	for _, site := range instanceCfg.InstanceSites {
+		if site.SiteName != name {
+			continue
+		}
+		if err := CheckoutSite(instanceCfg, site, benchDir, dbRootUser, dbRootPass); err != nil {
+			fmt.Printf("[ERROR] Failed to entirely checkout site %s: %v\n", site.SiteName, err)
+			RecordError(site.SiteName, "checkout", err)
+			return err
+		}
+		changed, err := CheckoutDomains(site, benchDir)
+		if err != nil {
+			fmt.Printf("[ERROR] Failed to align domains for site %s: %v\n", site.SiteName, err)
+			RecordError(site.SiteName, "domains", err)
+			return err
+		}
+		recordResult(site.SiteName, func(r *SiteResult) { r.DomainsChange = changed })
+		return nil
+	}
+	return fmt.Errorf("site %s is not listed in instance.json", name)
+}
+
 // CheckoutSite ensures a site exists and is properly configured.
-func CheckoutSite(site config.InstanceSite, benchDir, dbRootUser, dbRootPass string) error {
-	if _, err := os.Stat(filepath.Join(benchDir, "sites", site.SiteName)); os.IsNotExist(err) {
+func CheckoutSite(instanceCfg *config.InstanceConfig, site config.InstanceSite, benchDir, dbRootUser, dbRootPass string) error {
+	restored, cloned := false, false
+	_, statErr := os.Stat(filepath.Join(benchDir, "sites", site.SiteName))
+	if os.IsNotExist(statErr) && site.RestoreFrom != "" {
+		if err := Restore(site, instanceCfg.ObjectStorage, benchDir, dbRootUser, dbRootPass); err != nil {
+			fmt.Printf("[ERROR] Failed to restore site %s: %v\n", site.SiteName, err)
+			return err
+		}
+		restored = true
+		recordResult(site.SiteName, func(r *SiteResult) { r.Restored = true })
+	} else if os.IsNotExist(statErr) && site.CloneFrom != "" {
+		if err := Clone(site, benchDir, dbRootUser, dbRootPass); err != nil {
+			fmt.Printf("[ERROR] Failed to clone site %s: %v\n", site.SiteName, err)
+			return err
+		}
+		// The copy is migrated like a restored site once its apps are aligned
+		restored, cloned = true, true
+	} else if os.IsNotExist(statErr) {
 		fmt.Printf("[SITES] Creating: %s\n", site.SiteName)
-		if err := New(site.SiteName, dbRootUser, dbRootPass); err != nil {
+		// Apps installed at creation time must exist in the bench beforehand
+		if err := fetchMissingApps(site.InstallApps, benchDir); err != nil {
+			fmt.Printf("[ERROR] Failed to fetch creation apps for site %s: %v\n", site.SiteName, err)
+			return err
+		}
+		if err := New(site, dbRootUser, dbRootPass); err != nil {
 			fmt.Printf("[ERROR] Failed to create site %s: %v\n", site.SiteName, err)
 			return err
 		}
+		recordResult(site.SiteName, func(r *SiteResult) { r.Created = true })
+		recordMigratedCommits(site.SiteName)
 	}
 
 	if err := CheckoutApps(site, benchDir); err != nil {
@@ -56,5 +136,55 @@ func CheckoutSite(site config.InstanceSite, benchDir, dbRootUser, dbRootPass str
 		return err
 	}
 
+	// A restored database may predate the bench's app code
+	if restored {
+		if err := Migrate(site.SiteName); err != nil {
+			fmt.Printf("[ERROR] Failed to migrate restored site %s: %v\n", site.SiteName, err)
+			return err
+		}
+	}
+
+	if err := CheckoutSiteConfig(site, benchDir); err != nil {
+		fmt.Printf("[ERROR] Failed to reconcile site_config for site %s: %v\n", site.SiteName, err)
+		return err
+	}
+
+	if err := CheckoutScheduler(site); err != nil {
+		fmt.Printf("[ERROR] Failed to set the scheduler of site %s: %v\n", site.SiteName, err)
+		return err
+	}
+
+	// Clone paused the scheduler until the copy was aligned with its own declaration
+	if cloned {
+		if err := ShortHandRunOnSite(site.SiteName, "scheduler", "resume"); err != nil {
+			fmt.Printf("[ERROR] Failed to resume the scheduler of clone %s: %v\n", site.SiteName, err)
+			return err
+		}
+	}
+
 	return nil
 }
+
+// cloneBatches splits sites into batches that can be reconciled in parallel: sites that are
+// not clones first, then clones of those, then clones of clones, so that every source
+// exists before its copy is made. clone_from cycles are rejected by Validate.
+func cloneBatches(instanceCfg *config.InstanceConfig, names []string) [][]string {
+	depth := func(name string) int {
+		d := 0
+		site, ok := siteByName(instanceCfg, name)
+		for ok && site.CloneFrom != "" && d < len(instanceCfg.InstanceSites) {
+			d++
+			site, ok = siteByName(instanceCfg, site.CloneFrom)
+		}
+		return d
+	}
+	var batches [][]string
+	for _, name := range names {
+		d := depth(name)
+		for len(batches) <= d {
+			batches = append(batches, nil)
+		}
+		batches[d] = append(batches[d], name)
+	}
+	return batches
+}
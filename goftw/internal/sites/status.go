@@ -0,0 +1,159 @@
+package sites
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"goftw/internal/bench"
+	"goftw/internal/config"
+	"goftw/internal/utils"
+)
+
+// SiteStatus is the observed state of a site compared with instance.json
+type SiteStatus struct {
+	Site              string     `json:"site"`
+	Declared          bool       `json:"declared"`
+	Exists            bool       `json:"exists"`
+	DeclaredApps      []string   `json:"declared_apps,omitempty"`
+	InstalledApps     []string   `json:"installed_apps,omitempty"`
+	MissingApps       []string   `json:"missing_apps,omitempty"`
+	ExtraApps         []string   `json:"extra_apps,omitempty"`
+	PendingMigrations []string   `json:"pending_migrations,omitempty"`
+	MigrationsKnown   bool       `json:"migrations_known"`
+	Scheduler         string     `json:"scheduler,omitempty"` // enabled, disabled, paused or unknown
+	SchedulerDeclared *bool      `json:"scheduler_declared,omitempty"`
+	SchedulerDrift    bool       `json:"scheduler_drift,omitempty"`
+	MaintenanceMode   bool       `json:"maintenance_mode"`
+	WouldDrop         bool       `json:"would_drop"`
+	Protected         bool       `json:"protected,omitempty"`
+	AbandonedSince    *time.Time `json:"abandoned_since,omitempty"` // archived under the archive policy
+	Errors            []string   `json:"errors,omitempty"`
+}
+
+// Drifted reports whether the site differs from instance.json or needs attention
+func (s SiteStatus) Drifted() bool {
+	return !s.Exists || (!s.Declared && !s.Protected) || len(s.MissingApps) > 0 || len(s.ExtraApps) > 0 ||
+		len(s.PendingMigrations) > 0 || s.SchedulerDrift || len(s.Errors) > 0
+}
+
+// Status inspects every declared site and every site on disk without changing anything.
+// Queries that need the database are skipped when dbReachable is false. Warnings are
+// written to logs; problems with a single site are reported in its status instead.
+func Status(instanceCfg *config.InstanceConfig, benchDir string, dbReachable bool, logs io.Writer) ([]SiteStatus, error) {
+	onDisk, err := bench.ListSites(benchDir)
+	if err != nil {
+		fmt.Fprintf(logs, "[ERROR] Failed to list current sites: %v\n", err)
+		return nil, err
+	}
+	commits, err := bench.AppCommits(benchDir)
+	if err != nil {
+		fmt.Fprintf(logs, "[WARN] Could not read app commits: %v\n", err)
+	}
+
+	abandoned, err := loadAbandoned()
+	if err != nil {
+		fmt.Fprintf(logs, "[WARN] Could not read abandoned sites: %v\n", err)
+	}
+
+	var out []SiteStatus
+	for _, site := range instanceCfg.InstanceSites {
+		st := SiteStatus{Site: site.SiteName, Declared: true, DeclaredApps: append([]string{}, site.Apps...), SchedulerDeclared: site.SchedulerEnabled}
+		sort.Strings(st.DeclaredApps)
+		if _, err := os.Stat(filepath.Join(benchDir, "sites", site.SiteName, "site_config.json")); err == nil {
+			st.Exists = true
+			inspectSite(&st, benchDir, commits, dbReachable)
+			if st.InstalledApps != nil {
+				st.MissingApps = withoutFrappe(utils.Difference(st.DeclaredApps, st.InstalledApps))
+				st.ExtraApps = withoutFrappe(utils.Difference(st.InstalledApps, st.DeclaredApps))
+			}
+		}
+		out = append(out, st)
+	}
+	for _, name := range onDisk {
+		if siteExistsInCfx(name, instanceCfg) {
+			continue
+		}
+		st := SiteStatus{Site: name, Exists: true, Protected: IsProtected(instanceCfg, name)}
+		st.WouldDrop = instanceCfg.DropAbandonedSites && !st.Protected
+		if rec, ok := abandoned[name]; ok {
+			since := rec.Since
+			st.AbandonedSince = &since
+		}
+		inspectSite(&st, benchDir, commits, dbReachable)
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+// inspectSite fills in the observed state of a site that exists on disk
+func inspectSite(st *SiteStatus, benchDir string, commits map[string]string, dbReachable bool) {
+	cfg, err := readSiteConfig(benchDir, st.Site)
+	if err != nil {
+		st.Errors = append(st.Errors, err.Error())
+	} else {
+		st.MaintenanceMode = truthy(cfg["maintenance_mode"])
+	}
+
+	st.Scheduler = "unknown"
+	if !dbReachable {
+		return
+	}
+
+	apps, err := listAppsQuiet(st.Site)
+	if err != nil {
+		st.Errors = append(st.Errors, fmt.Sprintf("list-apps: %v", err))
+	} else {
+		st.InstalledApps = utils.ExtractAppNames(apps)
+		sort.Strings(st.InstalledApps)
+		if commits != nil {
+			pending, known, err := pendingMigrations(st.Site, st.InstalledApps, commits)
+			if err != nil {
+				st.Errors = append(st.Errors, fmt.Sprintf("pending migrations: %v", err))
+			}
+			st.PendingMigrations, st.MigrationsKnown = pending, known
+		}
+	}
+
+	enabled, err := schedulerEnabled(st.Site)
+	switch {
+	case err != nil:
+		st.Errors = append(st.Errors, fmt.Sprintf("scheduler status: %v", err))
+	case cfg != nil && truthy(cfg["pause_scheduler"]):
+		st.Scheduler = "paused"
+	case enabled:
+		st.Scheduler = "enabled"
+	default:
+		st.Scheduler = "disabled"
+	}
+	if err == nil && st.SchedulerDeclared != nil && *st.SchedulerDeclared != enabled {
+		st.SchedulerDrift = true
+	}
+}
+
+// truthy interprets the 0/1 or boolean flags frappe keeps in site_config.json
+func truthy(v any) bool {
+	switch t := v.(type) {
+	case bool:
+		return t
+	case float64:
+		return t != 0
+	case string:
+		return t == "1" || strings.EqualFold(t, "true")
+	}
+	return false
+}
+
+func withoutFrappe(apps []string) []string {
+	var out []string
+	for _, app := range apps {
+		if app != "frappe" {
+			out = append(out, app)
+		}
+	}
+	return out
+}
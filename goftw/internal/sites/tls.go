@@ -0,0 +1,21 @@
+package sites
+
+import (
+	"fmt"
+
+	"goftw/internal/certs"
+	"goftw/internal/config"
+)
+
+// CheckoutTLS provisions a certificate for every site, covering its name and domains.
+// The generated nginx config serves a site over HTTPS once its certificate exists.
+func CheckoutTLS(instanceCfg *config.InstanceConfig) error {
+	for _, site := range instanceCfg.InstanceSites {
+		// nginx is not running yet during reconciliation, so ACME uses the standalone challenge
+		if _, err := certs.Ensure(instanceCfg.TLS, site.SiteName, certs.SiteNames(site), true); err != nil {
+			fmt.Printf("[ERROR] Failed to provision certificate for site %s: %v\n", site.SiteName, err)
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+
+	"goftw/internal/environ"
+)
+
+// Path returns the full path of a file inside the goftw state directory.
+func Path(name string) string {
+	return filepath.Join(environ.GetStatePath(), name)
+}
+
+// Load decodes a JSON state file into v. A missing file leaves v untouched.
+func Load(name string, v any) error {
+	data, err := os.ReadFile(Path(name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse state file %s: %v", name, err)
+	}
+	return nil
+}
+
+// Save atomically writes v as JSON into a state file.
+func Save(name string, v any) error {
+	path := Path(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %v", err)
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	// A unique temp file keeps concurrent writers from renaming each other's half-written data
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write state file %s: %v", name, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write state file %s: %v", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Prune deletes all but the newest keep state files whose names start with prefix, such as
+// "reports/app-update-". Names must sort by age, as the timestamped reports do.
+func Prune(prefix string, keep int) error {
+	matches, err := filepath.Glob(Path(prefix) + "*.json")
+	if err != nil {
+		return err
+	}
+	sort.Strings(matches)
+	for len(matches) > keep {
+		if err := os.Remove(matches[0]); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		matches = matches[1:]
+	}
+	return nil
+}
+
+// Lock takes an exclusive advisory lock on a state file so that concurrent goftw
+// processes (the entrypoint and a manual command) do not interleave updates.
+// The returned function releases the lock.
+func Lock(name string) (func(), error) {
+	path := Path(name + ".lock")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %v", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock %s: %v", path, err)
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
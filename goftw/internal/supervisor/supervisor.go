@@ -3,8 +3,12 @@ package supervisor
 import (
 	"fmt"
 	"goftw/internal/bench"
+	"goftw/internal/certs"
+	"goftw/internal/config"
+	"goftw/internal/nginx"
 	"goftw/internal/sudo"
 	"os"
+	"path/filepath"
 )
 
 // SetupSupervisor sets up supervisor for the bench, merges configs, and starts supervisord.
@@ -54,37 +58,59 @@ func SetupSupervisor(benchDir string) error {
 	return nil
 }
 
-// SetupNginx sets up nginx using bench and symlinks the config.
-func SetupNginx(benchDir string) error {
+// SetupNginx renders the nginx config for all sites, swaps it into the bench and symlinks it.
+// The config is validated with `nginx -t` and rolled back if invalid.
+func SetupNginx(instanceCfg *config.InstanceConfig, benchDir string) error {
 	nginxConf := benchDir + "/config/nginx.conf"
 	nginxConfDest := "/etc/nginx/conf.d/frappe-bench.conf"
-	mainPatch := "/main.patch.conf"
-	globalConf := "/etc/nginx/nginx.conf"
 
-	// Remove old configs/links to force regeneration
-	_ = sudo.RemoveFile(nginxConf)
-	_ = sudo.RemoveFile(nginxConfDest)
-
-	// Generate nginx config
-	if err := bench.RunInBenchPrintIO("setup", "nginx"); err != nil {
-		fmt.Printf("[ERROR] Failed to setup nginx: %v\n", err)
-		return fmt.Errorf("failed to setup nginx: %v", err)
+	commonCfg, err := config.LoadCommonSitesConfig(benchDir + "/sites/common_site_config.json")
+	if err != nil {
+		fmt.Printf("[ERROR] Failed to load bench common_site_config.json: %v\n", err)
+		return err
 	}
 
-	// Inject patch into global nginx.conf if not already present
-	checkCmd := []string{"grep", "-q", "log_format main", globalConf}
-	if err := sudo.RunPrintIO(checkCmd...); err != nil {
-		fmt.Printf("[PATCH] Injecting main log_format into %s\n", globalConf)
-		if err := sudo.RunPrintIO("sed", "-i", "/http {/r "+mainPatch, globalConf); err != nil {
-			fmt.Printf("[ERROR] Failed to inject main.patch.conf: %v\n", err)
-			// not fatal — continue
+	conf := nginx.Conf{
+		BenchName:     filepath.Base(benchDir),
+		SitesPath:     benchDir + "/sites",
+		WebserverPort: commonCfg.WebserverPort,
+		SocketIOPort:  commonCfg.SocketIOPort,
+		HTTPPort:      80,
+		HTTPTimeout:   commonCfg.HTTPTimeout,
+	}
+	if _, err := os.Stat(certs.ACMEWebroot()); err == nil {
+		conf.ACMEWebroot = certs.ACMEWebroot()
+	}
+	for _, site := range instanceCfg.InstanceSites {
+		ngx := nginx.Site{
+			Name:              site.SiteName,
+			ServerNames:       certs.SiteNames(site),
+			ClientMaxBodySize: site.Nginx.ClientMaxBodySize,
+			RateLimit:         site.Nginx.RateLimit,
+			RateLimitBurst:    site.Nginx.RateLimitBurst,
+			ExtraLocations:    site.Nginx.ExtraLocations,
+			Snippet:           site.Nginx.Snippet,
 		}
+		if ngx.ClientMaxBodySize == "" {
+			ngx.ClientMaxBodySize = "50m"
+		}
+		if instanceCfg.TLS != nil {
+			// Sites are served over plain HTTP until their certificate has been issued
+			certPath, keyPath := certs.Paths(instanceCfg.TLS, site.SiteName)
+			if _, err := os.Stat(certPath); err == nil {
+				ngx.SSLCertificate, ngx.SSLCertificateKey = certPath, keyPath
+			}
+		}
+		conf.Sites = append(conf.Sites, ngx)
 	}
 
-	// Symlink bench-generated config
-	err := sudo.RunPrintIO("ln", "-sf", nginxConf, nginxConfDest)
+	rendered, err := nginx.Render(conf)
 	if err != nil {
-		fmt.Printf("[ERROR] Failed to symlink nginx config: %v\n", err)
+		fmt.Printf("[ERROR] Failed to render nginx config: %v\n", err)
+		return err
+	}
+	if err := nginx.Apply(rendered, nginxConf, nginxConfDest); err != nil {
+		fmt.Printf("[ERROR] Failed to apply nginx config: %v\n", err)
 		return err
 	}
 
@@ -0,0 +1,138 @@
+package supervisor
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"goftw/internal/bench"
+	"goftw/internal/config"
+	"goftw/internal/sudo"
+)
+
+var (
+	gunicornWorkersFlag = regexp.MustCompile(`(\s)(-w|--workers)(\s+|=)\d+`)
+	queueFlag           = regexp.MustCompile(`--queue\s+\S+`)
+	programsLine        = regexp.MustCompile(`(?m)^programs=.*$`)
+	numprocsLine        = regexp.MustCompile(`(?m)^numprocs\s*=.*$`)
+)
+
+// WriteBenchConf regenerates the bench's supervisor config with `bench setup supervisor` and
+// applies the gunicorn and queue worker counts declared in instance.json.
+func WriteBenchConf(instanceCfg *config.InstanceConfig, commonCfg *config.CommonConfig, benchDir string) error {
+	supervisorConf := benchDir + "/config/supervisor.conf"
+
+	// Remove old config to force regeneration
+	_ = sudo.RemoveFile(supervisorConf)
+	if err := bench.RunInBenchPrintIO("setup", "supervisor", "--skip-redis"); err != nil {
+		fmt.Printf("[ERROR] Failed to setup supervisor: %v\n", err)
+		return fmt.Errorf("failed to setup supervisor: %v", err)
+	}
+	data, err := sudo.ReadFile(supervisorConf)
+	if err != nil {
+		fmt.Printf("[ERROR] Failed to read supervisor config: %v\n", err)
+		return err
+	}
+
+	workers := instanceCfg.Workers
+	if workers.Gunicorn == 0 {
+		// max_workers in common_site_config.json was never read before, it now sizes gunicorn
+		workers.Gunicorn = commonCfg.MaxWorkers
+	}
+	patched := patchWorkers(string(data), workers)
+
+	tmp := supervisorConf + ".goftw.tmp"
+	if err := os.WriteFile(tmp, []byte(patched), 0644); err != nil {
+		return fmt.Errorf("failed to write supervisor config: %v", err)
+	}
+	if err := os.Rename(tmp, supervisorConf); err != nil {
+		return err
+	}
+	fmt.Printf("[SUPERVISOR] Supervisor config written (gunicorn: %s, short: %s, default: %s, long: %s)\n",
+		countOrBench(workers.Gunicorn), countOrBench(workers.Short), countOrBench(workers.Default), countOrBench(workers.Long))
+	return nil
+}
+
+// patchWorkers rewrites the program sections generated by bench. A dedicated default-queue
+// worker is split off the short worker when a default count is declared.
+func patchWorkers(conf string, w config.WorkersConfig) string {
+	sections := splitSections(conf)
+	var out []string
+	var defaultProgram string
+	for _, s := range sections {
+		header := firstLine(s)
+		switch {
+		case strings.HasSuffix(header, "-frappe-web]") && w.Gunicorn > 0:
+			s = gunicornWorkersFlag.ReplaceAllString(s, "${1}-w "+strconv.Itoa(w.Gunicorn))
+		case strings.HasSuffix(header, "-frappe-short-worker]"):
+			if w.Default > 0 {
+				def := strings.Replace(s, "-frappe-short-worker]", "-frappe-default-worker]", 1)
+				def = queueFlag.ReplaceAllString(def, "--queue default")
+				def = setNumprocs(def, w.Default)
+				defaultProgram = strings.TrimSuffix(strings.TrimPrefix(firstLine(def), "[program:"), "]")
+				s = queueFlag.ReplaceAllString(s, "--queue short")
+				out = append(out, setNumprocs(s, w.Short), def)
+				continue
+			}
+			s = setNumprocs(s, w.Short)
+		case strings.HasSuffix(header, "-frappe-long-worker]"):
+			if w.Default > 0 {
+				s = queueFlag.ReplaceAllString(s, "--queue long")
+			}
+			s = setNumprocs(s, w.Long)
+		}
+		out = append(out, s)
+	}
+
+	result := strings.Join(out, "")
+	if defaultProgram != "" {
+		// Keep the new program in the workers group so supervisorctl manages it with the others
+		result = programsLine.ReplaceAllStringFunc(result, func(line string) string {
+			if strings.Contains(line, "-frappe-short-worker") && !strings.Contains(line, defaultProgram) {
+				return line + "," + defaultProgram
+			}
+			return line
+		})
+	}
+	return result
+}
+
+// splitSections cuts a supervisor config before every "[section]" header
+func splitSections(conf string) []string {
+	var sections []string
+	start := 0
+	for i := 0; i < len(conf); i++ {
+		if conf[i] == '[' && (i == 0 || conf[i-1] == '\n') && i > start {
+			sections = append(sections, conf[start:i])
+			start = i
+		}
+	}
+	return append(sections, conf[start:])
+}
+
+// setNumprocs sets the number of processes of a program section, 0 keeps bench's value
+func setNumprocs(section string, n int) string {
+	if n <= 0 {
+		return section
+	}
+	line := "numprocs=" + strconv.Itoa(n)
+	if numprocsLine.MatchString(section) {
+		return numprocsLine.ReplaceAllString(section, line)
+	}
+	header := firstLine(section)
+	return header + "\n" + line + strings.TrimPrefix(section, header)
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return strings.TrimSpace(line)
+}
+
+func countOrBench(n int) string {
+	if n <= 0 {
+		return "bench default"
+	}
+	return strconv.Itoa(n)
+}
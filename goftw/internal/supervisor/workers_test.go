@@ -0,0 +1,99 @@
+package supervisor
+
+import (
+	"strings"
+	"testing"
+
+	"goftw/internal/config"
+)
+
+// benchConf is an excerpt of what `bench setup supervisor` generates
+const benchConf = `; Notes:
+[program:frappe-bench-frappe-web]
+command=/home/frappe/frappe-bench/env/bin/gunicorn -b 127.0.0.1:8000 -w 9 --max-requests 5000 frappe.app:application --preload
+autostart=true
+
+[program:frappe-bench-frappe-short-worker]
+command=bench worker --queue short,default
+numprocs=1
+process_name=%(program_name)s-%(process_num)d
+
+[program:frappe-bench-frappe-long-worker]
+command=bench worker --queue long,default,short
+numprocs=1
+process_name=%(program_name)s-%(process_num)d
+
+[group:frappe-bench-workers]
+programs=frappe-bench-frappe-schedule,frappe-bench-frappe-short-worker,frappe-bench-frappe-long-worker
+`
+
+func TestPatchWorkers(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers config.WorkersConfig
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "nothing declared keeps bench's config",
+			workers: config.WorkersConfig{},
+			want:    []string{benchConf},
+		},
+		{
+			name:    "gunicorn workers",
+			workers: config.WorkersConfig{Gunicorn: 4},
+			want:    []string{"-b 127.0.0.1:8000 -w 4 --max-requests"},
+			notWant: []string{"-w 9"},
+		},
+		{
+			name:    "short and long counts",
+			workers: config.WorkersConfig{Short: 3, Long: 2},
+			want: []string{
+				"[program:frappe-bench-frappe-short-worker]\ncommand=bench worker --queue short,default\nnumprocs=3\n",
+				"[program:frappe-bench-frappe-long-worker]\ncommand=bench worker --queue long,default,short\nnumprocs=2\n",
+			},
+			notWant: []string{"frappe-default-worker"},
+		},
+		{
+			name:    "dedicated default workers",
+			workers: config.WorkersConfig{Default: 2},
+			want: []string{
+				"[program:frappe-bench-frappe-short-worker]\ncommand=bench worker --queue short\nnumprocs=1\n",
+				"[program:frappe-bench-frappe-default-worker]\ncommand=bench worker --queue default\nnumprocs=2\n",
+				"[program:frappe-bench-frappe-long-worker]\ncommand=bench worker --queue long\n",
+				"programs=frappe-bench-frappe-schedule,frappe-bench-frappe-short-worker,frappe-bench-frappe-long-worker,frappe-bench-frappe-default-worker\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		got := patchWorkers(benchConf, tt.workers)
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s: config lacks %q:\n%s", tt.name, want, got)
+			}
+		}
+		for _, notWant := range tt.notWant {
+			if strings.Contains(got, notWant) {
+				t.Errorf("%s: config contains %q:\n%s", tt.name, notWant, got)
+			}
+		}
+	}
+}
+
+func TestSetNumprocs(t *testing.T) {
+	tests := []struct {
+		section string
+		n       int
+		want    string
+	}{
+		{"[program:a]\nnumprocs=1\n", 4, "[program:a]\nnumprocs=4\n"},
+		{"[program:a]\nnumprocs = 1\n", 2, "[program:a]\nnumprocs=2\n"},
+		{"[program:a]\ncommand=x\n", 3, "[program:a]\nnumprocs=3\ncommand=x\n"},
+		{"[program:a]\nnumprocs=1\n", 0, "[program:a]\nnumprocs=1\n"},
+	}
+	for _, tt := range tests {
+		if got := setNumprocs(tt.section, tt.n); got != tt.want {
+			t.Errorf("setNumprocs(%q, %d) = %q, want %q", tt.section, tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package updates
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"goftw/internal/bench"
+	"goftw/internal/config"
+	"goftw/internal/cron"
+	"goftw/internal/db"
+	"goftw/internal/sites"
+	"goftw/internal/state"
+)
+
+// Update policies an app can have in instance.json
+const (
+	PolicyTrack  = "track"  // follows its branch on every automatic update (default)
+	PolicyPinned = "pinned" // never moves
+	PolicyManual = "manual" // only moves with `goftw-entry apps update`
+)
+
+// Policy returns the update policy of an app
+func Policy(cfg config.UpdatesConfig, app string) string {
+	if p, ok := cfg.Apps[app]; ok && p.Policy != "" {
+		return p.Policy
+	}
+	if cfg.DefaultPolicy != "" {
+		return cfg.DefaultPolicy
+	}
+	return PolicyTrack
+}
+
+// PinnedRefs returns the refs of pinned apps that declare one
+func PinnedRefs(cfg config.UpdatesConfig) map[string]string {
+	refs := map[string]string{}
+	for app, p := range cfg.Apps {
+		if p.Ref != "" && Policy(cfg, app) == PolicyPinned {
+			refs[app] = p.Ref
+		}
+	}
+	return refs
+}
+
+// InWindow reports whether automatic updates are allowed at t. Without windows they always are.
+func InWindow(cfg config.UpdatesConfig, t time.Time) bool {
+	if len(cfg.Windows) == 0 {
+		return true
+	}
+	for _, w := range cfg.Windows {
+		if windowOpen(w, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// windowOpen reports whether a window started within its duration before t
+func windowOpen(w config.UpdateWindow, t time.Time) bool {
+	schedule, err := cron.Parse(w.Schedule)
+	if err != nil {
+		return false
+	}
+	duration, err := time.ParseDuration(w.Duration)
+	if err != nil {
+		return false
+	}
+	start := t.Truncate(time.Minute)
+	for m := time.Duration(0); m <= duration; m += time.Minute {
+		if schedule.Matches(start.Add(-m)) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipped returns the apps an update leaves alone and why. A manual update moves the
+// requested apps (all non-pinned apps when none are named) regardless of windows.
+func skipped(cfg config.UpdatesConfig, apps, requested []string, manual bool, now time.Time) map[string]string {
+	wanted := map[string]bool{}
+	for _, app := range requested {
+		wanted[app] = true
+	}
+	inWindow := InWindow(cfg, now)
+
+	skip := map[string]string{}
+	for _, app := range apps {
+		policy := Policy(cfg, app)
+		switch {
+		case len(requested) > 0 && !wanted[app]:
+			skip[app] = "not requested"
+		case policy == PolicyPinned:
+			skip[app] = "pinned"
+		case manual:
+		case policy == PolicyManual:
+			skip[app] = "manual policy, run `goftw-entry apps update " + app + "`"
+		case !inWindow:
+			skip[app] = "outside the update windows"
+		}
+	}
+	return skip
+}
+
+// Run updates the bench's apps according to their policies. The sites an update can touch,
+// those with an app that may move installed or with migrations pending, go into maintenance
+// before any code is pulled and leave it once they are migrated; sites no app moved for are
+// not backed up or migrated. A failed migration is rolled back.
+func Run(instanceCfg *config.InstanceConfig, benchDir string, dbCfg db.Config, requested []string, manual bool) (err error) {
+	// A previous run that died mid-update left its sites in maintenance
+	if err := sites.RecoverMaintenance(); err != nil {
+		fmt.Printf("[ERROR] Failed to restore site states left by an interrupted update: %v\n", err)
+	}
+
+	apps, err := bench.ListApps(benchDir)
+	if err != nil {
+		return err
+	}
+	for _, app := range requested {
+		if Policy(instanceCfg.Updates, app) == PolicyPinned {
+			return fmt.Errorf("app %s is pinned, change its policy to update it", app)
+		}
+	}
+	skip := skipped(instanceCfg.Updates, apps, requested, manual, time.Now())
+	// Pinned apps follow their ref, inside windows or not
+	pins := map[string]string{}
+	for app, ref := range PinnedRefs(instanceCfg.Updates) {
+		if _, err := os.Stat(filepath.Join(benchDir, "apps", app)); err == nil {
+			pins[app] = ref
+		}
+	}
+	var moving []string
+	for _, app := range apps {
+		if _, pinned := pins[app]; pinned || (skip[app] == "" && !bench.IsLocalApp(benchDir, app)) {
+			moving = append(moving, app)
+		}
+	}
+
+	affected, err := sites.AffectedSites(benchDir, moving)
+	if err != nil {
+		return err
+	}
+	if len(affected) > 0 && !instanceCfg.DisableUpdateMaintenance {
+		restore, err := sites.EnterMaintenance(benchDir, affected)
+		if err != nil {
+			return fmt.Errorf("failed to enter maintenance before updating: %v", err)
+		}
+		// Sites go back to their previous states however the update ends
+		defer func() {
+			if rerr := restore(); rerr != nil {
+				fmt.Printf("[ERROR] Failed to restore site states after the update: %v\n", rerr)
+				if err == nil {
+					err = rerr
+				}
+			}
+		}()
+	}
+
+	previousCommits, err := bench.AppCommits(benchDir)
+	if err != nil {
+		return fmt.Errorf("failed to record app commits before update: %v", err)
+	}
+	if len(moving) > 0 {
+		// Workers and manual `apps` commands change the same checkouts
+		if err := bench.WithSharedLock(func() error {
+			_, err := bench.UpdateApps(benchDir, instanceCfg.Updates.AllowNonFastForward, skip, pins)
+			return err
+		}); err != nil {
+			fmt.Printf("[ERROR] Failed to update bench apps: %v\n", err)
+		}
+	} else {
+		fmt.Println("[APPS] No app to update")
+	}
+	currentCommits, err := bench.AppCommits(benchDir)
+	if err != nil {
+		return fmt.Errorf("failed to read app commits after update: %v", err)
+	}
+	moved := sites.MovedApps(previousCommits, currentCommits)
+
+	targets, err := sites.SitesToMigrate(benchDir, moved)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		fmt.Println("[SITES] No site needs a migration")
+		return nil
+	}
+	fmt.Printf("[SITES] Migrating %d site(s) after changes to %v\n", len(targets), moved)
+	if err := sites.MigrateSites(benchDir, targets, instanceCfg.MaxParallelSites, previousCommits, dbCfg.User, dbCfg.Password); err != nil {
+		fmt.Printf("[ERROR] %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// WindowLoop runs an automatic update whenever an update window opens. It never returns
+// and is meant to run in a goroutine. Windows are read on every tick, so windows added by
+// a hot reload take effect without a restart.
+func WindowLoop(live *config.Live, benchDir string, dbCfg db.Config) {
+	for {
+		// Wake up at the start of every minute
+		now := time.Now()
+		time.Sleep(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+		tick := time.Now()
+		instanceCfg := live.Instance()
+
+		opened := false
+		for _, w := range instanceCfg.Updates.Windows {
+			if s, err := cron.Parse(w.Schedule); err == nil && s.Matches(tick) {
+				opened = true
+			}
+		}
+		if !opened {
+			continue
+		}
+
+		fmt.Println("[APPS] Update window opened, updating apps")
+		unlock, err := state.Lock("reconcile")
+		if err != nil {
+			fmt.Printf("[ERROR] Failed to lock reconciliation: %v\n", err)
+			continue
+		}
+		if err := Run(instanceCfg, benchDir, dbCfg, nil, false); err != nil {
+			fmt.Printf("[ERROR] Scheduled app update failed: %v\n", err)
+		}
+		unlock()
+	}
+}
@@ -0,0 +1,97 @@
+package updates
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"goftw/internal/config"
+)
+
+func TestWindowOpen(t *testing.T) {
+	// 2024-01-15 is a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, time.January, day, hour, minute, 30, 0, time.UTC)
+	}
+	nightly := config.UpdateWindow{Schedule: "0 2 * * *", Duration: "2h"}
+	tests := []struct {
+		name   string
+		window config.UpdateWindow
+		at     time.Time
+		want   bool
+	}{
+		{"at the start", nightly, at(15, 2, 0), true},
+		{"inside", nightly, at(15, 3, 15), true},
+		{"at the end", nightly, at(15, 4, 0), true},
+		{"after the end", nightly, at(15, 4, 1), false},
+		{"before the start", nightly, at(15, 1, 59), false},
+		{"across midnight", config.UpdateWindow{Schedule: "30 23 * * *", Duration: "1h"}, at(16, 0, 15), true},
+		{"weekday only, on sunday", config.UpdateWindow{Schedule: "0 2 * * 1-5", Duration: "1h"}, at(14, 2, 30), false},
+		{"weekday only, on monday", config.UpdateWindow{Schedule: "0 2 * * 1-5", Duration: "1h"}, at(15, 2, 30), true},
+		{"invalid schedule", config.UpdateWindow{Schedule: "bad", Duration: "1h"}, at(15, 2, 0), false},
+		{"invalid duration", config.UpdateWindow{Schedule: "0 2 * * *", Duration: "soon"}, at(15, 2, 0), false},
+	}
+	for _, tt := range tests {
+		if got := windowOpen(tt.window, tt.at); got != tt.want {
+			t.Errorf("%s: windowOpen = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSkipped(t *testing.T) {
+	inside := time.Date(2024, time.January, 15, 2, 30, 0, 0, time.UTC)
+	outside := time.Date(2024, time.January, 15, 12, 0, 0, 0, time.UTC)
+	cfg := config.UpdatesConfig{
+		Apps: map[string]config.AppUpdateConfig{
+			"erpnext": {Policy: PolicyPinned, Ref: "v15.0.0"},
+			"hrms":    {Policy: PolicyManual},
+		},
+		Windows: []config.UpdateWindow{{Schedule: "0 2 * * *", Duration: "1h"}},
+	}
+	apps := []string{"frappe", "erpnext", "hrms"}
+	outsideWindows := "outside the update windows"
+	manualPolicy := "manual policy, run `goftw-entry apps update hrms`"
+
+	tests := []struct {
+		name      string
+		cfg       config.UpdatesConfig
+		requested []string
+		manual    bool
+		now       time.Time
+		want      map[string]string
+	}{
+		{"inside a window", cfg, nil, false, inside,
+			map[string]string{"erpnext": "pinned", "hrms": manualPolicy}},
+		{"outside the windows", cfg, nil, false, outside,
+			map[string]string{"frappe": outsideWindows, "erpnext": "pinned", "hrms": manualPolicy}},
+		{"no windows", config.UpdatesConfig{}, nil, false, outside,
+			map[string]string{}},
+		{"manual update ignores windows and manual policies", cfg, nil, true, outside,
+			map[string]string{"erpnext": "pinned"}},
+		{"manual update of named apps", cfg, []string{"hrms"}, true, outside,
+			map[string]string{"frappe": "not requested", "erpnext": "not requested"}},
+		{"pinned by default", config.UpdatesConfig{DefaultPolicy: PolicyPinned}, nil, true, outside,
+			map[string]string{"frappe": "pinned", "erpnext": "pinned", "hrms": "pinned"}},
+	}
+	for _, tt := range tests {
+		got := skipped(tt.cfg, apps, tt.requested, tt.manual, tt.now)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: skipped = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPinnedRefs(t *testing.T) {
+	cfg := config.UpdatesConfig{
+		DefaultPolicy: PolicyPinned,
+		Apps: map[string]config.AppUpdateConfig{
+			"erpnext": {Ref: "v15.0.0"},
+			"hrms":    {Policy: PolicyTrack, Ref: "ignored"},
+			"crm":     {Policy: PolicyPinned},
+		},
+	}
+	want := map[string]string{"erpnext": "v15.0.0"}
+	if got := PinnedRefs(cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("PinnedRefs = %v, want %v", got, want)
+	}
+}
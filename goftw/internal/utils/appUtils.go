@@ -1,6 +1,10 @@
 package utils
 
-import "goftw/internal/entity"
+import (
+	"goftw/internal/entity"
+	"regexp"
+	"strings"
+)
 
 // extractAppNames extracts only the Name field from []AppInfo
 func ExtractAppNames(apps []entity.AppInfo) []string {
@@ -10,3 +14,39 @@ func ExtractAppNames(apps []entity.AppInfo) []string {
 	}
 	return names
 }
+
+// ParseAppList parses `bench --site <site> list-apps` output into []AppInfo.
+func ParseAppList(out string) []entity.AppInfo {
+	lines := strings.Split(out, "\n")
+	apps := make([]entity.AppInfo, 0)
+
+	// Regex for full format: name <version> (<commit>) [branch]
+	reFull := regexp.MustCompile(`^(\w+)\s+([\w\.\-]+)?\s*(?:\(([\da-f]+)\))?\s*(?:\[(.+)\])?$`)
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		match := reFull.FindStringSubmatch(line)
+		if match != nil {
+			apps = append(apps, entity.AppInfo{
+				Name:    match[1],
+				Version: match[2],
+				Commit:  match[3],
+				Branch:  match[4],
+				Raw:     line,
+			})
+			continue
+		}
+
+		// Fallback: just the name
+		apps = append(apps, entity.AppInfo{
+			Name: strings.Fields(line)[0],
+			Raw:  line,
+		})
+	}
+
+	return apps
+}
@@ -14,3 +14,26 @@ func Difference(a, b []string) []string {
 	}
 	return diff
 }
+
+// Intersect returns items of a that are also in b
+func Intersect(a, b []string) []string {
+	mb := map[string]bool{}
+	for _, x := range b {
+		mb[x] = true
+	}
+	var both []string
+	for _, x := range a {
+		if mb[x] {
+			both = append(both, x)
+		}
+	}
+	return both
+}
+
+// YesNo renders a flag for the status and summary tables
+func YesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
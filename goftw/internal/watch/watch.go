@@ -0,0 +1,183 @@
+package watch
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"goftw/internal/config"
+	"goftw/internal/db"
+	"goftw/internal/notify"
+	"goftw/internal/sites"
+	"goftw/internal/state"
+)
+
+// Loop periodically compares the bench with instance.json and reports or reconciles
+// drift. The watch block is read from the live config on every check, so a hot reload can
+// enable, change or disable the watcher. It never returns and is meant to run in a goroutine
+// next to the deployed services.
+func Loop(live *config.Live, benchDir string, dbCfg db.Config) {
+	if cfg := live.Instance().Watch; cfg != nil {
+		fmt.Printf("[WATCH] Checking sites for drift every %s (auto reconcile: %v)\n", interval(cfg), cfg.AutoReconcile)
+	}
+
+	last := ""
+	for {
+		time.Sleep(interval(live.Instance().Watch))
+		instanceCfg := live.Instance()
+		cfg := instanceCfg.Watch
+		if cfg == nil {
+			last = ""
+			continue
+		}
+
+		events, err := check(instanceCfg, benchDir, dbCfg)
+		if err != nil {
+			fmt.Printf("[ERROR] Drift check failed: %v\n", err)
+			continue
+		}
+
+		// Drift that survived the previous reconcile is reported once, not retried every check
+		if len(events) > 0 && cfg.AutoReconcile && strings.Join(events, "\n") != last {
+			fmt.Printf("[WATCH] Drift found, reconciling %d event(s)\n", len(events))
+			reconciled := reconcile(instanceCfg, benchDir, dbCfg)
+			remaining, err := check(instanceCfg, benchDir, dbCfg)
+			if err != nil {
+				fmt.Printf("[ERROR] Drift check failed: %v\n", err)
+				continue
+			}
+			subject := "Drift reconciled"
+			if len(remaining) > 0 {
+				subject = "Drift remains after reconciling"
+			}
+			_ = notify.Send(cfg, notify.New(subject, append(prefix("found: ", events), append(reconciled, prefix("remaining: ", remaining)...)...)))
+			last = strings.Join(remaining, "\n")
+			continue
+		}
+
+		// Only notify when the set of drift events changes, not on every check
+		current := strings.Join(events, "\n")
+		if current == last {
+			continue
+		}
+		if len(events) == 0 {
+			fmt.Println("[WATCH] Drift resolved")
+			_ = notify.Send(cfg, notify.New("Drift resolved", []string{"sites match instance.json again"}))
+		} else {
+			for _, e := range events {
+				fmt.Printf("[WATCH] %s\n", e)
+			}
+			_ = notify.Send(cfg, notify.New(fmt.Sprintf("%d drift event(s)", len(events)), events))
+		}
+		last = current
+	}
+}
+
+// interval returns how long the watcher waits between checks, 5 minutes by default
+func interval(cfg *config.WatchConfig) time.Duration {
+	if cfg == nil || cfg.Interval == "" {
+		return 5 * time.Minute
+	}
+	d, err := time.ParseDuration(cfg.Interval)
+	if err != nil || d <= 0 {
+		fmt.Printf("[ERROR] Invalid watch interval %q, using 5m\n", cfg.Interval)
+		return 5 * time.Minute
+	}
+	return d
+}
+
+// check describes every difference between the bench and instance.json
+func check(instanceCfg *config.InstanceConfig, benchDir string, dbCfg db.Config) ([]string, error) {
+	if err := db.Ping(dbCfg); err != nil {
+		return []string{fmt.Sprintf("database %s:%s is not reachable: %v", dbCfg.Host, dbCfg.Port, err)}, nil
+	}
+	statuses, err := sites.Status(instanceCfg, benchDir, true, os.Stdout)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []string
+	for _, st := range statuses {
+		switch {
+		case st.Protected:
+		case !st.Declared && st.WouldDrop:
+			events = append(events, fmt.Sprintf("site %s exists but is not in instance.json (would be dropped)", st.Site))
+		case !st.Declared:
+			events = append(events, fmt.Sprintf("site %s exists but is not in instance.json", st.Site))
+		case !st.Exists:
+			events = append(events, fmt.Sprintf("site %s is declared but does not exist", st.Site))
+		}
+		for _, app := range st.ExtraApps {
+			events = append(events, fmt.Sprintf("app %s is installed on site %s but not declared", app, st.Site))
+		}
+		for _, app := range st.MissingApps {
+			events = append(events, fmt.Sprintf("app %s is declared for site %s but not installed", app, st.Site))
+		}
+		if len(st.PendingMigrations) > 0 {
+			events = append(events, fmt.Sprintf("site %s has pending migrations for %s", st.Site, strings.Join(st.PendingMigrations, ", ")))
+		}
+		for _, e := range st.Errors {
+			events = append(events, fmt.Sprintf("site %s: %s", st.Site, e))
+		}
+	}
+	sort.Strings(events)
+	return events, nil
+}
+
+// reconcile checks out every site and describes the outcome per site
+func reconcile(instanceCfg *config.InstanceConfig, benchDir string, dbCfg db.Config) []string {
+	// Do not interleave with a hot reload reconciling at the same time
+	unlock, err := state.Lock("reconcile")
+	if err != nil {
+		return []string{fmt.Sprintf("reconcile failed: %v", err)}
+	}
+	defer unlock()
+	sites.ResetResults()
+	var out []string
+	if err := sites.CheckoutSites(instanceCfg, benchDir, dbCfg.User, dbCfg.Password); err != nil {
+		out = append(out, fmt.Sprintf("reconcile failed: %v", err))
+	}
+	sites.Summarize()
+	for _, r := range sites.Results() {
+		var did []string
+		if r.Created {
+			did = append(did, "created")
+		}
+		if r.Restored {
+			did = append(did, "restored")
+		}
+		if r.Dropped {
+			did = append(did, "dropped")
+		}
+		if len(r.AppsInstalled) > 0 {
+			did = append(did, "installed "+strings.Join(r.AppsInstalled, ", "))
+		}
+		if len(r.AppsRemoved) > 0 {
+			did = append(did, "uninstalled "+strings.Join(r.AppsRemoved, ", "))
+		}
+		if r.ConfigChanged {
+			did = append(did, "updated site_config")
+		}
+		if r.DomainsChange {
+			did = append(did, "updated domains")
+		}
+		if r.Migrated {
+			did = append(did, "migrated")
+		}
+		did = append(did, r.Errors...)
+		if len(did) > 0 {
+			out = append(out, fmt.Sprintf("reconciled %s: %s", r.Site, strings.Join(did, "; ")))
+		}
+	}
+	return out
+}
+
+func prefix(p string, lines []string) []string {
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = p + l
+	}
+	return out
+}
@@ -0,0 +1,140 @@
+package workers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Result is the outcome of one job
+type Result struct {
+	Name     string
+	Output   []byte
+	Err      error
+	Skipped  bool // not started because an earlier job failed
+	Duration time.Duration
+	// ResultData is what the job wrote to the file passed in GOFTW_RESULT_FILE, nil if nothing
+	ResultData []byte
+}
+
+// RunSelf runs `goftw-entry <args(name)...>` for every name with at most parallel jobs at once.
+// Each job is a separate process, so a crash in one site's reconciliation cannot affect another.
+// Output of each job is buffered and printed as one block, in the order of names, so the log
+// reads the same regardless of scheduling; with a single worker output is streamed as it happens.
+// With stopOnError, no new job is started once one has failed.
+func RunSelf(names []string, parallel int, stopOnError bool, args func(name string) []string) []Result {
+	self, err := os.Executable()
+	if err != nil {
+		results := make([]Result, len(names))
+		for i, name := range names {
+			results[i] = Result{Name: name, Err: fmt.Errorf("cannot locate goftw executable: %v", err)}
+		}
+		return results
+	}
+	if parallel < 1 {
+		parallel = 1
+	}
+	stream := parallel == 1
+	resultDir, err := os.MkdirTemp("", "goftw-workers-")
+	if err != nil {
+		results := make([]Result, len(names))
+		for i, name := range names {
+			results[i] = Result{Name: name, Err: fmt.Errorf("cannot create worker result directory: %v", err)}
+		}
+		return results
+	}
+	defer os.RemoveAll(resultDir)
+
+	results := make([]Result, len(names))
+	done := make([]chan struct{}, len(names))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	var mu sync.Mutex
+	failed := false
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		sem <- struct{}{}
+		mu.Lock()
+		skip := stopOnError && failed
+		mu.Unlock()
+		if skip {
+			<-sem
+			results[i] = Result{Name: name, Skipped: true}
+			close(done[i])
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			defer close(done[i])
+
+			var buf bytes.Buffer
+			var out io.Writer = &buf
+			if stream {
+				out = os.Stdout
+			}
+			resultFile := filepath.Join(resultDir, fmt.Sprintf("%d.json", i))
+			start := time.Now()
+			cmd := exec.Command(self, args(name)...)
+			cmd.Env = append(os.Environ(), "GOFTW_RESULT_FILE="+resultFile)
+			cmd.Stdout = out
+			cmd.Stderr = out
+			err := cmd.Run()
+			data, _ := os.ReadFile(resultFile)
+			results[i] = Result{Name: name, Output: buf.Bytes(), Err: err, Duration: time.Since(start), ResultData: data}
+			if err != nil {
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+			}
+		}(i, name)
+	}
+
+	// Print each block as soon as it and every block before it are complete
+	for i := range names {
+		<-done[i]
+		printResult(results[i], stream)
+	}
+	wg.Wait()
+	return results
+}
+
+func printResult(r Result, streamed bool) {
+	status := "ok"
+	switch {
+	case r.Skipped:
+		status = "skipped"
+	case r.Err != nil:
+		status = "failed: " + r.Err.Error()
+	}
+	if streamed {
+		fmt.Printf("[WORKERS] %s: %s (%s)\n", r.Name, status, r.Duration.Round(time.Second))
+		return
+	}
+	fmt.Printf("========== %s: %s (%s) ==========\n", r.Name, status, r.Duration.Round(time.Second))
+	os.Stdout.Write(r.Output)
+	if len(r.Output) > 0 && r.Output[len(r.Output)-1] != '\n' {
+		fmt.Println()
+	}
+}
+
+// Failed returns the results that failed or were skipped
+func Failed(results []Result) []Result {
+	var out []Result
+	for _, r := range results {
+		if r.Err != nil || r.Skipped {
+			out = append(out, r)
+		}
+	}
+	return out
+}
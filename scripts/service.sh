@@ -8,21 +8,30 @@ if [ "$DEPLOYMENT" = "production" ]; then
   sudo mkdir -p /var/log
   sudo chown -R frappe:frappe /var/log
 
-  # Remove old configs to force regeneration
-  sudo rm -f config/supervisor.conf
-  sudo rm -f config/nginx.conf
-  sudo rm -f /etc/nginx/conf.d/frappe-bench.conf
-
-  echo "[SETUP] Regenerating supervisor and nginx configs"
-  bench setup supervisor --skip-redis
-  bench setup nginx
-
-  if ! grep -q "log_format main" /etc/nginx/nginx.conf; then
-    echo "[PATCH] Injecting main log_format into /etc/nginx/nginx.conf"
-    sudo sed -i '/http {/r /main.patch.conf' /etc/nginx/nginx.conf || true
+  # goftw writes the supervisor config itself, with the worker counts from instance.json
+  if [ -z "${SUPERVISOR_CONF_MANAGED:-}" ]; then
+    # Remove old configs to force regeneration
+    sudo rm -f config/supervisor.conf
+
+    echo "[SETUP] Regenerating supervisor config"
+    bench setup supervisor --skip-redis
   fi
 
-  sudo ln -sf "$BENCH_DIR/config/nginx.conf" /etc/nginx/conf.d/frappe-bench.conf
+  # goftw renders and validates nginx itself; the shell entrypoint still relies on bench
+  if [ -z "${NGINX_CONF_MANAGED:-}" ]; then
+    sudo rm -f config/nginx.conf
+    sudo rm -f /etc/nginx/conf.d/frappe-bench.conf
+
+    echo "[SETUP] Regenerating nginx config"
+    bench setup nginx
+
+    if ! grep -q "log_format main" /etc/nginx/nginx.conf; then
+      echo "[PATCH] Injecting main log_format into /etc/nginx/nginx.conf"
+      sudo sed -i '/http {/r /main.patch.conf' /etc/nginx/nginx.conf || true
+    fi
+
+    sudo ln -sf "$BENCH_DIR/config/nginx.conf" /etc/nginx/conf.d/frappe-bench.conf
+  fi
 
   echo "[SUPERVISOR] Merging configs -> $MERGED_SUPERVISOR_CONF"
   sudo bash -c "cat /dev/null > '$MERGED_SUPERVISOR_CONF'"